@@ -0,0 +1,175 @@
+// filtercheck 以詞庫檔案過濾訊息並輸出結果，不需要資料庫或 Redis，方便調整詞庫與過濾設定。
+//
+//	go run ./cmd/filtercheck -dict combined_sensitive_words.xlsx "你這混蛋" "s.h.i.t"
+//	echo "caonima" | go run ./cmd/filtercheck -pinyin -json
+//
+// 沒有指定訊息時從標準輸入逐行讀取。大型詞庫可以先用 -save 存成編譯後的檔案，之後以 -load 直接讀取，
+// 不需要重新正規化與建立字典樹：
+//
+//	go run ./cmd/filtercheck -dict words.xlsx -save words.acd
+//	go run ./cmd/filtercheck -load words.acd "你這混蛋"
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"unicode/utf8"
+
+	"example.com/m/filter"
+	"example.com/m/filter/importer"
+)
+
+func main() {
+	options := filter.DefaultOptions()
+
+	dictPath := flag.String("dict", "./combined_sensitive_words.xlsx", "dictionary file (txt, csv, json or xlsx)")
+	allowPath := flag.String("allow", "", "allow-list file, one term per line")
+	sheet := flag.String("sheet", "", "xlsx sheet name, defaults to the first sheet")
+	column := flag.Int("column", -1, "column holding the words, -1 reads every cell")
+	header := flag.Bool("header", true, "first row is a header")
+	categoryActions := flag.String("actions", "", `category actions, e.g. "political=reject,spam=hold"`)
+	normalize := flag.String("normalize", "", `normalization steps, e.g. "nfkc,casefold", defaults to all`)
+	gaps := flag.String("gaps", "", `split detection filler characters, e.g. "whitespace,punctuation", defaults to all`)
+	flag.IntVar(&options.Split.MaxGap, "max-gap", options.Split.MaxGap, "maximum filler characters between two characters of a word")
+	flag.BoolVar(&options.Pinyin, "pinyin", false, "also match homophones and typed pinyin")
+	flag.BoolVar(&options.WordBoundary, "word-boundary", false, "require Latin words to appear at word boundaries")
+	flag.StringVar(&options.Mask.Strategy, "mask", options.Mask.Strategy, "mask strategy: full, keep-first or token")
+	maskChar := flag.String("mask-char", string(options.Mask.Char), "mask character for full and keep-first")
+	flag.StringVar(&options.Mask.Token, "mask-token", options.Mask.Token, "replacement for the token strategy")
+	flag.IntVar(&options.HoldSeverity, "hold-severity", 0, "hold messages whose highest severity reaches this value, 0 disables")
+	savePath := flag.String("save", "", "write the compiled dictionary to this file and exit")
+	loadPath := flag.String("load", "", "read a compiled dictionary written by -save instead of -dict and -allow")
+	asJSON := flag.Bool("json", false, "print each verdict as JSON")
+	flag.Parse()
+
+	if *categoryActions != "" {
+		options.CategoryActions = filter.ParseCategoryActions(*categoryActions)
+	}
+	if *normalize != "" {
+		options.Normalize = filter.ParseNormalizeSteps(*normalize)
+	}
+	if *gaps != "" {
+		options.Split.Gaps = filter.ParseGapSet(*gaps)
+	}
+	if !filter.IsValidMaskStrategy(options.Mask.Strategy) {
+		log.Fatalf("Invalid mask strategy %q", options.Mask.Strategy)
+	}
+	if char, size := utf8.DecodeRuneInString(*maskChar); size > 0 && char != utf8.RuneError {
+		options.Mask.Char = char
+	}
+
+	var (
+		dictionary *filter.Dictionary
+		err        error
+	)
+	if *loadPath != "" {
+		dictionary, err = readDictionary(*loadPath, options)
+	} else {
+		dictionary, err = buildDictionary(*dictPath, *allowPath, importer.Options{Sheet: *sheet, Column: *column, HasHeader: *header}, options)
+	}
+	if err != nil {
+		log.Fatalf("Error loading dictionary: %v", err)
+	}
+	log.Printf("Loaded %d words and %d allow-list terms", len(dictionary.Words()), len(dictionary.AllowList()))
+
+	if *savePath != "" {
+		if err := writeDictionary(*savePath, dictionary); err != nil {
+			log.Fatalf("Error saving dictionary: %v", err)
+		}
+		log.Printf("Compiled dictionary written to %s", *savePath)
+		return
+	}
+
+	check := func(message string) {
+		verdict := dictionary.Check(message, nil)
+		if *asJSON {
+			data, _ := json.Marshal(verdict)
+			fmt.Println(string(data))
+			return
+		}
+		for _, hit := range verdict.Hits {
+			for _, span := range hit.Spans {
+				fmt.Printf("檢測到敏感詞: %s (分類: %s, 處理: %s, 位置: %d-%d)\n", hit.Word, hit.Category, hit.Action, span.Start, span.End)
+			}
+		}
+		for _, hit := range verdict.Suppressed {
+			fmt.Printf("已排除: %s (原因: %s)\n", hit.Word, hit.Reason)
+		}
+		fmt.Printf("[%s] %s\n", verdict.Action, verdict.Content)
+	}
+
+	if flag.NArg() > 0 {
+		for _, message := range flag.Args() {
+			check(message)
+		}
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		check(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("Error reading messages: %v", err)
+	}
+}
+
+// buildDictionary 讀取詞庫與允許詞檔案並建立詞庫
+func buildDictionary(dictPath, allowPath string, importOptions importer.Options, options filter.Options) (*filter.Dictionary, error) {
+	entries, err := readEntries(dictPath, importOptions)
+	if err != nil {
+		return nil, err
+	}
+	var allowTerms []string
+	if allowPath != "" {
+		allowEntries, err := readEntries(allowPath, importer.DefaultOptions())
+		if err != nil {
+			return nil, fmt.Errorf("allow-list: %w", err)
+		}
+		for _, entry := range allowEntries {
+			allowTerms = append(allowTerms, entry.Word)
+		}
+	}
+	return filter.NewDictionary(entries, allowTerms, options), nil
+}
+
+// readDictionary 讀取 -save 寫入的編譯後詞庫
+func readDictionary(path string, options filter.Options) (*filter.Dictionary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return filter.ReadDictionary(f, options)
+}
+
+// writeDictionary 將編譯後的詞庫寫入檔案
+func writeDictionary(path string, dictionary *filter.Dictionary) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := dictionary.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readEntries 依副檔名讀取詞庫檔案
+func readEntries(path string, options importer.Options) ([]filter.Entry, error) {
+	wordImporter, err := importer.ForFile(path, options)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return wordImporter.Import(f)
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuditFilter 查詢稽核紀錄的條件，空值表示不限制
+type AuditFilter struct {
+	Actor      string
+	Action     string // 完整的動作名稱，或前綴，例如 "sanction" 符合 "sanction.mute"
+	TargetType string
+	Target     string
+	Room       string
+	Since      time.Time
+	Until      time.Time
+	Limit      int
+	Offset     int
+}
+
+// RecordAudit 新增一筆稽核紀錄
+func RecordAudit(entry *AuditEntry) error {
+	return PgConn.QueryRow(Ctx, `
+		INSERT INTO audit_log (actor, action, target_type, target, room, reason, before, after, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at`,
+		entry.Actor, entry.Action, entry.TargetType, entry.Target, entry.Room, entry.Reason, entry.Before, entry.After, entry.RequestID).
+		Scan(&entry.ID, &entry.CreatedAt)
+}
+
+// ListAuditEntries 依條件由新到舊列出稽核紀錄，並返回符合條件的總數
+func ListAuditEntries(filter AuditFilter) ([]AuditEntry, int, error) {
+	const where = `
+		WHERE ($1 = '' OR actor = $1)
+		AND ($2 = '' OR action = $2 OR action LIKE $8 || '.%' ESCAPE '\')
+		AND ($3 = '' OR target_type = $3)
+		AND ($4 = '' OR target = $4)
+		AND ($5 = '' OR room = $5)
+		AND ($6::timestamptz IS NULL OR created_at >= $6)
+		AND ($7::timestamptz IS NULL OR created_at < $7)`
+	args := []interface{}{filter.Actor, filter.Action, filter.TargetType, filter.Target, filter.Room, nullIfZeroTime(filter.Since), nullIfZeroTime(filter.Until),
+		escapeLike(filter.Action)}
+
+	var total int
+	if err := PgConn.QueryRow(Ctx, "SELECT COUNT(*) FROM audit_log"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := PgConn.Query(Ctx, `
+		SELECT id, actor, action, target_type, target, room, reason, before, after, request_id, created_at
+		FROM audit_log`+where+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT $9 OFFSET $10`, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var entry AuditEntry
+		if err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.TargetType, &entry.Target, &entry.Room, &entry.Reason,
+			&entry.Before, &entry.After, &entry.RequestID, &entry.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total, rows.Err()
+}
+
+// auditCSVHeader 匯出 CSV 的欄位
+var auditCSVHeader = []string{"id", "created_at", "actor", "action", "target_type", "target", "room", "reason", "before", "after", "request_id"}
+
+// WriteAuditCSV 以 CSV 格式輸出稽核紀錄，第一列為欄位名稱，時間為 RFC 3339 格式的 UTC 時間。
+// 以 =、+、-、@ 開頭的欄位會加上 ' 前綴，避免在試算表中被當成公式執行
+func WriteAuditCSV(w io.Writer, entries []AuditEntry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(auditCSVHeader); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		record := []string{
+			strconv.FormatInt(entry.ID, 10),
+			entry.CreatedAt.UTC().Format(time.RFC3339),
+			csvSafe(entry.Actor),
+			entry.Action,
+			entry.TargetType,
+			csvSafe(entry.Target),
+			csvSafe(entry.Room),
+			csvSafe(entry.Reason),
+			string(entry.Before),
+			string(entry.After),
+			csvSafe(entry.RequestID),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func nullIfZeroTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
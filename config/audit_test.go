@@ -0,0 +1,37 @@
+package config_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"example.com/m/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAuditCSV(t *testing.T) {
+	entries := []config.AuditEntry{
+		{
+			ID:         2,
+			Actor:      "mod",
+			Action:     "sanction.mute",
+			TargetType: "user",
+			Target:     "alice",
+			Room:       "general",
+			Reason:     "flooding, again",
+			After:      json.RawMessage(`{"expiresAt":"2024-01-01T13:00:00Z"}`),
+			RequestID:  "req-1",
+			CreatedAt:  time.Date(2024, 1, 1, 20, 0, 0, 0, time.FixedZone("CST", 8*3600)),
+		},
+		{ID: 1, Actor: "system", Action: "word.add", TargetType: "word", Target: "=HYPERLINK(\"x\")", CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, config.WriteAuditCSV(&buf, entries))
+	assert.Equal(t, "id,created_at,actor,action,target_type,target,room,reason,before,after,request_id\n"+
+		"2,2024-01-01T12:00:00Z,mod,sanction.mute,user,alice,general,\"flooding, again\",,\"{\"\"expiresAt\"\":\"\"2024-01-01T13:00:00Z\"\"}\",req-1\n"+
+		"1,2024-01-01T00:00:00Z,system,word.add,word,\"'=HYPERLINK(\"\"x\"\")\",,,,,\n", // 公式開頭的欄位加上 ' 前綴
+		buf.String())
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"unicode/utf8"
+
+	"example.com/m/filter"
+)
+
+// FilterSettings 建立詞庫時使用的過濾設定，從環境變數讀取：
+//
+//	FILTER_CATEGORY_ACTIONS  各分類的處理方式，例如 "political=reject,spam=hold"
+//	FILTER_NORMALIZE         正規化步驟，例如 "nfkc,casefold"，預設全部啟用
+//	FILTER_SPLIT_GAPS        拆字檢測允許的填充字元種類，預設全部
+//	FILTER_SPLIT_MAX_GAP     兩個字元之間最多允許的填充字元數，預設 3
+//	FILTER_PINYIN            設為 true 時啟用拼音比對
+//	FILTER_WORD_BOUNDARY     設為 true 時拉丁字母的敏感詞必須出現在單字邊界
+//	FILTER_MASK_STRATEGY     遮蔽方式：full、keep-first 或 token
+//	FILTER_MASK_CHAR         full 與 keep-first 使用的遮蔽字元
+//	FILTER_MASK_TOKEN        token 使用的替換字串
+//	FILTER_HOLD_SEVERITY     命中的最高嚴重程度達到此值時送交人工審核，預設不啟用
+var FilterSettings = LoadFilterOptions()
+
+// LoadFilterOptions 從環境變數讀取過濾設定，未設定的項目使用 filter.DefaultOptions 的值
+func LoadFilterOptions() filter.Options {
+	options := filter.DefaultOptions()
+	options.CategoryActions = filter.ParseCategoryActions(os.Getenv("FILTER_CATEGORY_ACTIONS"))
+	if value, ok := os.LookupEnv("FILTER_NORMALIZE"); ok {
+		options.Normalize = filter.ParseNormalizeSteps(value)
+	}
+
+	if gaps := os.Getenv("FILTER_SPLIT_GAPS"); gaps != "" {
+		options.Split.Gaps = filter.ParseGapSet(gaps)
+	}
+	if maxGap, err := strconv.Atoi(os.Getenv("FILTER_SPLIT_MAX_GAP")); err == nil && maxGap >= 0 {
+		options.Split.MaxGap = maxGap
+	}
+
+	options.Pinyin = os.Getenv("FILTER_PINYIN") == "true"
+	options.WordBoundary = os.Getenv("FILTER_WORD_BOUNDARY") == "true"
+
+	if strategy := os.Getenv("FILTER_MASK_STRATEGY"); filter.IsValidMaskStrategy(strategy) {
+		options.Mask.Strategy = strategy
+	}
+	if char, size := utf8.DecodeRuneInString(os.Getenv("FILTER_MASK_CHAR")); size > 0 && char != utf8.RuneError {
+		options.Mask.Char = char
+	}
+	if token := os.Getenv("FILTER_MASK_TOKEN"); token != "" {
+		options.Mask.Token = token
+	}
+	if severity, err := strconv.Atoi(os.Getenv("FILTER_HOLD_SEVERITY")); err == nil && severity > 0 {
+		options.HoldSeverity = severity
+	}
+	return options
+}
@@ -0,0 +1,60 @@
+package config_test
+
+import (
+	"testing"
+
+	"example.com/m/config"
+	"example.com/m/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadFilterOptions(t *testing.T) {
+	assert.Equal(t, filter.DefaultOptions(), config.LoadFilterOptions())
+
+	t.Setenv("FILTER_CATEGORY_ACTIONS", "political=reject")
+	t.Setenv("FILTER_NORMALIZE", "nfkc,casefold")
+	t.Setenv("FILTER_SPLIT_GAPS", "whitespace")
+	t.Setenv("FILTER_SPLIT_MAX_GAP", "1")
+	t.Setenv("FILTER_PINYIN", "true")
+	t.Setenv("FILTER_WORD_BOUNDARY", "true")
+	t.Setenv("FILTER_MASK_STRATEGY", filter.MaskKeepFirst)
+	t.Setenv("FILTER_MASK_CHAR", "#")
+	t.Setenv("FILTER_HOLD_SEVERITY", "3")
+
+	options := config.LoadFilterOptions()
+	assert.Equal(t, filter.ActionReject, options.CategoryActions[filter.CategoryPolitical])
+	assert.Equal(t, filter.NormalizeNFKC|filter.NormalizeCaseFold, options.Normalize)
+	assert.Equal(t, filter.SplitOptions{Gaps: filter.GapWhitespace, MaxGap: 1}, options.Split)
+	assert.True(t, options.Pinyin)
+	assert.True(t, options.WordBoundary)
+	assert.Equal(t, filter.MaskOptions{Strategy: filter.MaskKeepFirst, Char: '#', Token: "***"}, options.Mask)
+	assert.Equal(t, 3, options.HoldSeverity)
+}
+
+func TestFilterMessageUsesCurrentDictionary(t *testing.T) {
+	config.Ac.Store(filter.NewDictionary([]filter.Entry{{Word: "damn"}}, nil, filter.DefaultOptions()))
+	assert.Equal(t, "**** it", config.FilterMessage("damn it").Content)
+
+	policy := &filter.Policy{AllowWords: []string{"damn"}}
+	assert.Equal(t, filter.ActionAllow, config.FilterMessageForRoom("", "damn it", policy).Action)
+}
+
+func TestFilterMessageForRoomRebuildsChangedRoomWords(t *testing.T) {
+	config.Ac.Store(filter.NewDictionary(nil, nil, filter.DefaultOptions()))
+	policy := &filter.Policy{Words: []filter.Entry{{Word: "jira"}}}
+	assert.Equal(t, "**** it", config.FilterMessageForRoom("general", "jira it", policy).Content)
+
+	// 其他實例更新的房間詞與快取不同，會重新建立
+	policy.Words = []filter.Entry{{Word: "asana"}}
+	assert.Equal(t, "jira it", config.FilterMessageForRoom("general", "jira it", policy).Content)
+	assert.Equal(t, "***** it", config.FilterMessageForRoom("general", "asana it", policy).Content)
+
+	// 全域詞庫重新加載後使用新的設定重新建立
+	options := filter.DefaultOptions()
+	options.Mask.Char = '#'
+	config.Ac.Store(filter.NewDictionary(nil, nil, options))
+	assert.Equal(t, "##### it", config.FilterMessageForRoom("general", "asana it", policy).Content)
+
+	config.InvalidateRoomDictionary("general")
+	assert.Equal(t, "##### it", config.FilterMessageForRoom("general", "asana it", policy).Content)
+}
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadMetricsToken 讀取 /metrics 的抓取憑證：優先讀取 METRICS_TOKEN_FILE 指向的檔案（例如 Docker secret），
+// 其次為 METRICS_TOKEN。都未設定時返回空字串，/metrics 只接受 admin 或 bot 角色的 JWT
+func LoadMetricsToken() (string, error) {
+	if path := os.Getenv("METRICS_TOKEN_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", fmt.Errorf("metrics token file %s is empty", path)
+		}
+		return token, nil
+	}
+	return strings.TrimSpace(os.Getenv("METRICS_TOKEN")), nil
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"example.com/m/filter"
+	"example.com/m/moderation"
+)
+
+// ModerationSettings 檢舉與審核的相關設定
+type ModerationSettings struct {
+	ReportHideThreshold int // 不同用戶的檢舉數達到此值時自動隱藏訊息，0 表示不自動隱藏
+	ReportContext       int // 審核被檢舉的訊息時，附上前後各幾則訊息
+}
+
+// LoadModerationSettings 從環境變數讀取審核設定
+func LoadModerationSettings() ModerationSettings {
+	settings := ModerationSettings{
+		ReportHideThreshold: 3,
+		ReportContext:       5,
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("REPORT_HIDE_THRESHOLD")); err == nil && v >= 0 {
+		settings.ReportHideThreshold = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("REPORT_CONTEXT_MESSAGES")); err == nil && v >= 0 {
+		settings.ReportContext = v
+	}
+
+	return settings
+}
+
+// LoadModerationHook 從環境變數建立外部審核服務，未設定 MODERATION_HOOK_URL 時返回 nil：
+//
+//	MODERATION_HOOK_URL         接收 JSON 審核請求的網址
+//	MODERATION_HOOK_TOKEN       以 Bearer token 送出，預設不送
+//	MODERATION_HOOK_MODE        sync（發布前審核，預設）或 async（發布後審核）
+//	MODERATION_HOOK_TIMEOUT_MS  每次呼叫的逾時毫秒數，預設 500
+//	MODERATION_HOOK_ON_FAILURE  服務失敗或逾時時：open（放行，預設）、closed（拒絕）或 hold（送交人工審核）
+func LoadModerationHook() *moderation.Hook {
+	url := os.Getenv("MODERATION_HOOK_URL")
+	if url == "" {
+		return nil
+	}
+
+	hook := &moderation.Hook{
+		Provider: moderation.NewWebhookProvider(url, os.Getenv("MODERATION_HOOK_TOKEN")),
+		Mode:     moderation.ModeSync,
+		Timeout:  500 * time.Millisecond,
+		OnError:  filter.ActionAllow,
+	}
+	if mode, err := moderation.ParseMode(os.Getenv("MODERATION_HOOK_MODE")); err == nil {
+		hook.Mode = mode
+	} else {
+		Logger.Warnf("Ignoring MODERATION_HOOK_MODE: %v", err)
+	}
+	if v, err := strconv.Atoi(os.Getenv("MODERATION_HOOK_TIMEOUT_MS")); err == nil && v > 0 {
+		hook.Timeout = time.Duration(v) * time.Millisecond
+	}
+	if action, err := moderation.ParseFailurePolicy(os.Getenv("MODERATION_HOOK_ON_FAILURE")); err == nil {
+		hook.OnError = action
+	} else {
+		Logger.Warnf("Ignoring MODERATION_HOOK_ON_FAILURE: %v", err)
+	}
+	return hook
+}
@@ -0,0 +1,40 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"example.com/m/config"
+	"example.com/m/filter"
+	"example.com/m/moderation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadModerationSettings(t *testing.T) {
+	assert.Equal(t, config.ModerationSettings{ReportHideThreshold: 3, ReportContext: 5}, config.LoadModerationSettings())
+
+	t.Setenv("REPORT_HIDE_THRESHOLD", "0")
+	t.Setenv("REPORT_CONTEXT_MESSAGES", "-1")
+	assert.Equal(t, config.ModerationSettings{ReportHideThreshold: 0, ReportContext: 5}, config.LoadModerationSettings())
+}
+
+func TestLoadModerationHook(t *testing.T) {
+	assert.Nil(t, config.LoadModerationHook())
+
+	t.Setenv("MODERATION_HOOK_URL", "http://classifier.internal/check")
+	hook := config.LoadModerationHook()
+	assert.Equal(t, moderation.ModeSync, hook.Mode)
+	assert.Equal(t, 500*time.Millisecond, hook.Timeout)
+	assert.Equal(t, filter.ActionAllow, hook.OnError)
+
+	t.Setenv("MODERATION_HOOK_MODE", "async")
+	t.Setenv("MODERATION_HOOK_TIMEOUT_MS", "2000")
+	t.Setenv("MODERATION_HOOK_ON_FAILURE", "closed")
+	hook = config.LoadModerationHook()
+	assert.True(t, hook.Async())
+	assert.Equal(t, 2*time.Second, hook.Timeout)
+	assert.Equal(t, filter.ActionReject, hook.OnError)
+
+	t.Setenv("MODERATION_HOOK_ON_FAILURE", "sometimes")
+	assert.Equal(t, filter.ActionAllow, config.LoadModerationHook().OnError) // 格式錯誤時保留預設值
+}
@@ -2,10 +2,14 @@ package config
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"time"
 
+	"example.com/m/filter"
+	"example.com/m/moderation"
+	"example.com/m/spam"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -15,6 +19,114 @@ type ChatMessage struct {
 	Sender  string    `json:"sender"`  // Sender name
 	Content string    `json:"content"` // Message content
 	Time    time.Time `json:"time"`    // Message sending time
+
+	Attachments []int64    `json:"attachments,omitempty"` // Attachment IDs referenced by the message
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`   // When the message self-destructs
+	Flagged     bool       `json:"-"`                     // Allowed by the filter but marked for review
+	Hidden      bool       `json:"hidden,omitempty"`      // Hidden after user reports, only shown to moderators
+}
+
+type MessageReport struct {
+	ID        int64     `json:"id"`        // Report ID
+	MessageID int64     `json:"messageId"` // Reported message
+	Reporter  string    `json:"reporter"`  // User who reported the message
+	Reason    string    `json:"reason"`    // Reason given by the reporter
+	CreatedAt time.Time `json:"createdAt"` // When the message was reported
+}
+
+type ModerationItem struct {
+	ID          int64               `json:"id"`                    // Queue item ID
+	Room        string              `json:"room"`                  // Room the message was sent to
+	Sender      string              `json:"sender"`                // Sender name
+	Content     string              `json:"content"`               // Message content as it will be published, masked by the filter
+	Original    string              `json:"original,omitempty"`    // Unmasked message content, for items held before publishing
+	Reason      string              `json:"reason"`                // Why the message was held, e.g. "filter"
+	Categories  []string            `json:"categories"`            // Filter categories that were hit
+	Severity    int                 `json:"severity"`              // Highest severity of the hits
+	Hits        []filter.Hit        `json:"hits"`                  // Filter hits with their positions
+	Signals     []spam.Signal       `json:"signals,omitempty"`     // Spam rules that were triggered, for items held by spam detection
+	Classifier  *moderation.Verdict `json:"classifier,omitempty"`  // External classifier verdict, for items queued by the moderation hook
+	Attachments []int64             `json:"attachments,omitempty"` // Attachment IDs referenced by the message
+	ExpiresIn   int64               `json:"expiresIn"`             // Requested message lifetime in seconds
+	Time        time.Time           `json:"time"`                  // When the message was sent
+	Status      string              `json:"status"`                // pending, approved or rejected
+	MessageID   *int64              `json:"messageId,omitempty"`   // Published message after approval
+	ReviewedBy  string              `json:"reviewedBy,omitempty"`  // Moderator who reviewed the message
+	ReviewedAt  *time.Time          `json:"reviewedAt,omitempty"`  // When the message was reviewed
+	Note        string              `json:"note,omitempty"`        // Moderator's note, sent to the sender on rejection
+	CreatedAt   time.Time           `json:"createdAt"`             // When the message was queued
+
+	Reports []MessageReport `json:"reports,omitempty"` // User reports, for items queued by reports
+	Context []ChatMessage   `json:"context,omitempty"` // Surrounding messages, for items queued by reports
+}
+
+type Sanction struct {
+	ID        int64      `json:"id"`                  // Sanction ID
+	Kind      string     `json:"kind"`                // mute, kick or ban
+	Username  string     `json:"username"`            // Sanctioned user
+	Room      string     `json:"room,omitempty"`      // Room the sanction applies to, empty for global
+	Reason    string     `json:"reason,omitempty"`    // Reason given by the moderator
+	CreatedBy string     `json:"createdBy"`           // Moderator who issued the sanction
+	CreatedAt time.Time  `json:"createdAt"`           // When the sanction was issued
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // When the sanction ends, nil for permanent
+}
+
+type AuditEntry struct {
+	ID         int64           `json:"id"`                  // Audit entry ID
+	Actor      string          `json:"actor"`               // User who performed the action, "system" for automatic actions
+	Action     string          `json:"action"`              // What was done, e.g. "sanction.mute" or "word.remove"
+	TargetType string          `json:"targetType"`          // Kind of object acted on, e.g. "user", "message" or "word"
+	Target     string          `json:"target"`              // Identifier of the object acted on
+	Room       string          `json:"room,omitempty"`      // Room the action applies to, empty for global actions
+	Reason     string          `json:"reason,omitempty"`    // Reason given by the actor
+	Before     json.RawMessage `json:"before,omitempty"`    // State before the change
+	After      json.RawMessage `json:"after,omitempty"`     // State after the change
+	RequestID  string          `json:"requestId,omitempty"` // X-Request-ID of the API request
+	CreatedAt  time.Time       `json:"createdAt"`           // When the action was recorded
+}
+
+type Attachment struct {
+	ID           int64     `json:"id"`           // Attachment ID
+	Room         string    `json:"room"`         // Room the attachment was uploaded to
+	Uploader     string    `json:"uploader"`     // Uploader name
+	FileName     string    `json:"fileName"`     // Original file name
+	MimeType     string    `json:"mimeType"`     // Detected MIME type
+	Size         int64     `json:"size"`         // Size in bytes
+	Checksum     string    `json:"checksum"`     // SHA-256 checksum (hex)
+	StorageKey   string    `json:"-"`            // Key in the blob storage
+	ThumbnailKey string    `json:"-"`            // Key of the generated thumbnail, if any
+	HasThumbnail bool      `json:"hasThumbnail"` // Whether a thumbnail is available
+	MessageID    *int64    `json:"messageId"`    // Message the attachment is linked to
+	Time         time.Time `json:"time"`         // Upload time
+}
+
+type Room struct {
+	Name             string     `json:"name"`             // Room name
+	Announcement     string     `json:"announcement"`     // Announcement banner shown to joiners
+	AnnouncementBy   string     `json:"announcementBy"`   // Who posted the announcement
+	AnnouncementTime *time.Time `json:"announcementTime"` // When the announcement was posted
+	RetentionSeconds int64      `json:"retentionSeconds"` // Default message lifetime, 0 keeps messages forever
+
+	FilterPolicy filter.Policy `json:"-"` // Room-specific sensitive word filter settings
+}
+
+type RoomPin struct {
+	Room     string      `json:"room"`     // Room name
+	Message  ChatMessage `json:"message"`  // Pinned message
+	PinnedBy string      `json:"pinnedBy"` // Who pinned the message
+	PinnedAt time.Time   `json:"pinnedAt"` // When the message was pinned
+}
+
+type ScheduledJob struct {
+	ID        int64     `json:"id"`                  // Job ID
+	Kind      string    `json:"kind"`                // "message" or "reminder"
+	Username  string    `json:"username"`            // Owner of the job
+	Room      string    `json:"room"`                // Target room
+	Content   string    `json:"content"`             // Message content or reminder note
+	MessageID *int64    `json:"messageId,omitempty"` // Message a reminder refers to
+	RunAt     time.Time `json:"runAt"`               // When the job is due
+	Status    string    `json:"status"`              // pending, running, undelivered, done, failed or cancelled
+	CreatedAt time.Time `json:"createdAt"`           // Creation time
 }
 
 func InitDB() (*pgxpool.Pool, error) {
@@ -53,6 +165,12 @@ func checkAndCreateTable(db *pgxpool.Pool, tableName, createTableSQL string) err
 	return nil
 }
 
+// ensureColumn adds a column introduced after the table was first created
+func ensureColumn(db *pgxpool.Pool, tableName, column, definition string) error {
+	_, err := db.Exec(context.Background(), fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", tableName, column, definition))
+	return err
+}
+
 // checkAndCreateTableChat checks and creates the chat table
 func CheckAndCreateTableChat(db *pgxpool.Pool) error {
 	// Check and create the chat table
@@ -65,14 +183,13 @@ func CheckAndCreateTableChat(db *pgxpool.Pool) error {
 		email VARCHAR(100),
 		time TIMESTAMPTZ DEFAULT NOW()
 	);
-	
-	INSERT INTO users (username, password, phone, email) 
-	VALUES ('test', '$2a$10$9L8NySy0Udrc9GpFAPLha.rcoxuZe9UW2Qk7mjPivD2P8PfgIn3Sy', '10000000000', 'test@gmail.com');
-	
 	`
 	if err := checkAndCreateTable(db, "users", chatTableSQL); err != nil {
 		return err
 	}
+	if err := ensureColumn(db, "users", "roles", "TEXT[] NOT NULL DEFAULT '{member}'"); err != nil {
+		return err
+	}
 
 	chatTableSQL = `
 		CREATE TABLE chat_messages (
@@ -85,6 +202,18 @@ func CheckAndCreateTableChat(db *pgxpool.Pool) error {
 	if err := checkAndCreateTable(db, "chat_messages", chatTableSQL); err != nil {
 		return err
 	}
+	if err := ensureColumn(db, "chat_messages", "expires_at", "TIMESTAMPTZ"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "chat_messages", "flagged", "BOOLEAN NOT NULL DEFAULT FALSE"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "chat_messages", "hidden", "BOOLEAN NOT NULL DEFAULT FALSE"); err != nil {
+		return err
+	}
+	if _, err := db.Exec(context.Background(), "CREATE INDEX IF NOT EXISTS chat_messages_expires_at_idx ON chat_messages (expires_at) WHERE expires_at IS NOT NULL"); err != nil {
+		return err
+	}
 
 	chatTableSQL = `
 		CREATE TABLE sensitive_words (
@@ -94,6 +223,261 @@ func CheckAndCreateTableChat(db *pgxpool.Pool) error {
 	if err := checkAndCreateTable(db, "sensitive_words", chatTableSQL); err != nil {
 		return err
 	}
+	if err := ensureColumn(db, "sensitive_words", "source", "VARCHAR(20) NOT NULL DEFAULT 'excel'"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "sensitive_words", "updated_at", "TIMESTAMPTZ DEFAULT NOW()"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "sensitive_words", "category", "VARCHAR(20)"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "sensitive_words", "severity", "INTEGER"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "sensitive_words", "action", "VARCHAR(20)"); err != nil {
+		return err
+	}
+
+	chatTableSQL = `
+		CREATE TABLE dictionary_imports (
+		id SERIAL PRIMARY KEY,
+		file_path VARCHAR(255) NOT NULL,
+		checksum CHAR(64) NOT NULL,
+		word_count INTEGER NOT NULL,
+		added INTEGER NOT NULL,
+		removed INTEGER NOT NULL,
+		imported_at TIMESTAMPTZ DEFAULT NOW()
+	);`
+	if err := checkAndCreateTable(db, "dictionary_imports", chatTableSQL); err != nil {
+		return err
+	}
+
+	chatTableSQL = `
+		CREATE TABLE sensitive_word_allowlist (
+		term VARCHAR(255) PRIMARY KEY,
+		added_by VARCHAR(50),
+		created_at TIMESTAMPTZ DEFAULT NOW()
+	);`
+	if err := checkAndCreateTable(db, "sensitive_word_allowlist", chatTableSQL); err != nil {
+		return err
+	}
+
+	chatTableSQL = `
+		CREATE TABLE room_members (
+		room VARCHAR(255) NOT NULL,
+		username VARCHAR(50) NOT NULL,
+		role VARCHAR(20) NOT NULL DEFAULT 'member',
+		joined_at TIMESTAMPTZ DEFAULT NOW(),
+		PRIMARY KEY (room, username)
+	);`
+	if err := checkAndCreateTable(db, "room_members", chatTableSQL); err != nil {
+		return err
+	}
+
+	chatTableSQL = `
+		CREATE TABLE rooms (
+		name VARCHAR(255) PRIMARY KEY,
+		announcement TEXT NOT NULL DEFAULT '',
+		announcement_by VARCHAR(50),
+		announcement_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ DEFAULT NOW()
+	);`
+	if err := checkAndCreateTable(db, "rooms", chatTableSQL); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "rooms", "retention_seconds", "BIGINT NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "rooms", "filter_policy", "JSONB NOT NULL DEFAULT '{}'"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "rooms", "created_by", "VARCHAR(50)"); err != nil {
+		return err
+	}
+	// 有訊息或成員但沒有 rooms 紀錄的舊房間先登記為沒有擁有者的房間，之後加入的用戶不會因此成為擁有者
+	if _, err := db.Exec(context.Background(), `
+		INSERT INTO rooms (name)
+		SELECT room FROM chat_messages UNION SELECT room FROM room_members
+		ON CONFLICT (name) DO NOTHING`); err != nil {
+		return err
+	}
+
+	chatTableSQL = `
+		CREATE TABLE room_pins (
+		room VARCHAR(255) NOT NULL,
+		message_id INTEGER NOT NULL REFERENCES chat_messages(id) ON DELETE CASCADE,
+		pinned_by VARCHAR(50) NOT NULL,
+		pinned_at TIMESTAMPTZ DEFAULT NOW(),
+		PRIMARY KEY (room, message_id)
+	);`
+	if err := checkAndCreateTable(db, "room_pins", chatTableSQL); err != nil {
+		return err
+	}
+
+	chatTableSQL = `
+		CREATE TABLE scheduled_jobs (
+		id BIGSERIAL PRIMARY KEY,
+		kind VARCHAR(20) NOT NULL,
+		username VARCHAR(50) NOT NULL,
+		room VARCHAR(255) NOT NULL,
+		content TEXT NOT NULL DEFAULT '',
+		message_id INTEGER REFERENCES chat_messages(id) ON DELETE CASCADE,
+		run_at TIMESTAMPTZ NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		locked_by VARCHAR(100),
+		locked_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ DEFAULT NOW()
+	);
+	
+	CREATE INDEX scheduled_jobs_due_idx ON scheduled_jobs (run_at) WHERE status IN ('pending', 'running');
+	`
+	if err := checkAndCreateTable(db, "scheduled_jobs", chatTableSQL); err != nil {
+		return err
+	}
+	if _, err := db.Exec(context.Background(), "CREATE INDEX IF NOT EXISTS scheduled_jobs_undelivered_idx ON scheduled_jobs (username) WHERE status = 'undelivered'"); err != nil {
+		return err
+	}
+
+	chatTableSQL = `
+		CREATE TABLE attachments (
+		id BIGSERIAL PRIMARY KEY,
+		room VARCHAR(255) NOT NULL,
+		uploader VARCHAR(50) NOT NULL,
+		file_name VARCHAR(255) NOT NULL,
+		mime_type VARCHAR(100) NOT NULL,
+		size BIGINT NOT NULL,
+		checksum CHAR(64) NOT NULL,
+		storage_key VARCHAR(255) NOT NULL,
+		thumbnail_key VARCHAR(255),
+		message_id INTEGER REFERENCES chat_messages(id) ON DELETE SET NULL,
+		time TIMESTAMPTZ DEFAULT NOW()
+	);
+	
+	CREATE INDEX attachments_message_id_idx ON attachments (message_id);
+	`
+	if err := checkAndCreateTable(db, "attachments", chatTableSQL); err != nil {
+		return err
+	}
+
+	chatTableSQL = `
+		CREATE TABLE moderation_queue (
+		id BIGSERIAL PRIMARY KEY,
+		room VARCHAR(255) NOT NULL,
+		sender VARCHAR(50) NOT NULL,
+		content TEXT NOT NULL,
+		reason VARCHAR(20) NOT NULL,
+		categories TEXT[] NOT NULL DEFAULT '{}',
+		severity INTEGER NOT NULL DEFAULT 0,
+		hits JSONB NOT NULL DEFAULT '[]',
+		attachments BIGINT[],
+		expires_in BIGINT NOT NULL DEFAULT 0,
+		message_time TIMESTAMPTZ NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		message_id INTEGER REFERENCES chat_messages(id) ON DELETE SET NULL,
+		reviewed_by VARCHAR(50),
+		reviewed_at TIMESTAMPTZ,
+		note TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ DEFAULT NOW()
+	);
+	
+	CREATE INDEX moderation_queue_room_status_idx ON moderation_queue (room, status, created_at);
+	`
+	if err := checkAndCreateTable(db, "moderation_queue", chatTableSQL); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "moderation_queue", "signals", "JSONB NOT NULL DEFAULT '[]'"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "moderation_queue", "classifier", "JSONB"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "moderation_queue", "original", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	// 同一則訊息只能有一筆待審核的檢舉項目；建立索引前先移除舊版本同時檢舉時重複建立的項目
+	_, err := db.Exec(context.Background(), `
+		DELETE FROM moderation_queue a USING moderation_queue b
+		WHERE a.reason = 'report' AND a.status = 'pending' AND b.reason = 'report' AND b.status = 'pending'
+			AND a.message_id = b.message_id AND a.id > b.id`)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(context.Background(), "CREATE UNIQUE INDEX IF NOT EXISTS moderation_queue_pending_report_idx ON moderation_queue (message_id) WHERE reason = 'report' AND status = 'pending'"); err != nil {
+		return err
+	}
+
+	chatTableSQL = `
+		CREATE TABLE user_sanctions (
+		id BIGSERIAL PRIMARY KEY,
+		kind VARCHAR(10) NOT NULL,
+		username VARCHAR(50) NOT NULL,
+		room VARCHAR(255) NOT NULL DEFAULT '',
+		reason TEXT NOT NULL DEFAULT '',
+		created_by VARCHAR(50) NOT NULL,
+		created_at TIMESTAMPTZ DEFAULT NOW(),
+		expires_at TIMESTAMPTZ,
+		revoked_by VARCHAR(50),
+		revoked_at TIMESTAMPTZ
+	);
+	
+	CREATE INDEX user_sanctions_active_idx ON user_sanctions (username, kind, room) WHERE revoked_at IS NULL;
+	`
+	if err := checkAndCreateTable(db, "user_sanctions", chatTableSQL); err != nil {
+		return err
+	}
+	// 房間踢出改為到期前有效；之前的踢出紀錄沒有到期時間，改為已到期，避免被當成永久踢出
+	if _, err := db.Exec(context.Background(), "UPDATE user_sanctions SET expires_at = created_at WHERE kind = 'kick' AND expires_at IS NULL"); err != nil {
+		return err
+	}
+
+	chatTableSQL = `
+		CREATE TABLE message_reports (
+		id BIGSERIAL PRIMARY KEY,
+		message_id INTEGER NOT NULL REFERENCES chat_messages(id) ON DELETE CASCADE,
+		reporter VARCHAR(50) NOT NULL,
+		reason TEXT NOT NULL,
+		created_at TIMESTAMPTZ DEFAULT NOW(),
+		UNIQUE (message_id, reporter)
+	);`
+	if err := checkAndCreateTable(db, "message_reports", chatTableSQL); err != nil {
+		return err
+	}
+
+	// 稽核紀錄只能新增，以觸發器禁止修改與刪除
+	chatTableSQL = `
+		CREATE TABLE audit_log (
+		id BIGSERIAL PRIMARY KEY,
+		actor VARCHAR(50) NOT NULL,
+		action VARCHAR(50) NOT NULL,
+		target_type VARCHAR(20) NOT NULL,
+		target VARCHAR(255) NOT NULL DEFAULT '',
+		room VARCHAR(255) NOT NULL DEFAULT '',
+		reason TEXT NOT NULL DEFAULT '',
+		before JSONB,
+		after JSONB,
+		request_id VARCHAR(64) NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX audit_log_created_at_idx ON audit_log (created_at);
+	CREATE INDEX audit_log_actor_idx ON audit_log (actor, created_at);
+	CREATE INDEX audit_log_target_idx ON audit_log (target_type, target, created_at);
+
+	CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+	BEGIN
+		RAISE EXCEPTION 'audit_log is append-only';
+	END;
+	$$ LANGUAGE plpgsql;
+
+	CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+		FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+	`
+	if err := checkAndCreateTable(db, "audit_log", chatTableSQL); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"example.com/m/ratelimit"
+)
+
+// RateLimitSettings WebSocket 訊息的頻率限制與自動禁言設定
+type RateLimitSettings struct {
+	User       ratelimit.Bucket // 每個用戶（所有連線合計）
+	Room       ratelimit.Bucket // 每個房間（所有用戶合計）
+	Connection ratelimit.Bucket // 每個 WebSocket 連線
+
+	MuteAfter    int           // 在 MuteWindow 內被限制達到此次數時自動禁言，0 表示不自動禁言
+	MuteWindow   time.Duration // 計算被限制次數的時間範圍
+	MuteDuration time.Duration // 自動禁言的時間
+}
+
+// LoadRateLimitSettings 從環境變數讀取頻率限制設定。令牌桶的格式為 "burst:rate"，例如 "10:1"，"0" 表示不限制：
+//
+//	RATE_LIMIT_USER              預設 20:2
+//	RATE_LIMIT_ROOM              預設 100:20
+//	RATE_LIMIT_CONNECTION        預設 10:1
+//	RATE_LIMIT_MUTE_AFTER        預設 5
+//	RATE_LIMIT_MUTE_WINDOW       秒，預設 60
+//	RATE_LIMIT_MUTE_DURATION     秒，預設 300
+func LoadRateLimitSettings() RateLimitSettings {
+	settings := RateLimitSettings{
+		User:         ratelimit.Bucket{Burst: 20, Rate: 2},
+		Room:         ratelimit.Bucket{Burst: 100, Rate: 20},
+		Connection:   ratelimit.Bucket{Burst: 10, Rate: 1},
+		MuteAfter:    5,
+		MuteWindow:   time.Minute,
+		MuteDuration: 5 * time.Minute,
+	}
+
+	for env, bucket := range map[string]*ratelimit.Bucket{
+		"RATE_LIMIT_USER":       &settings.User,
+		"RATE_LIMIT_ROOM":       &settings.Room,
+		"RATE_LIMIT_CONNECTION": &settings.Connection,
+	} {
+		value, ok := os.LookupEnv(env)
+		if !ok {
+			continue
+		}
+		parsed, err := ratelimit.ParseBucket(value)
+		if err != nil {
+			Logger.Warnf("Ignoring %s: %v", env, err)
+			continue
+		}
+		*bucket = parsed
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("RATE_LIMIT_MUTE_AFTER")); err == nil && v >= 0 {
+		settings.MuteAfter = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("RATE_LIMIT_MUTE_WINDOW")); err == nil && v > 0 {
+		settings.MuteWindow = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("RATE_LIMIT_MUTE_DURATION")); err == nil && v > 0 {
+		settings.MuteDuration = time.Duration(v) * time.Second
+	}
+
+	return settings
+}
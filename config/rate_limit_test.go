@@ -0,0 +1,29 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"example.com/m/config"
+	"example.com/m/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadRateLimitSettings(t *testing.T) {
+	settings := config.LoadRateLimitSettings()
+	assert.Equal(t, ratelimit.Bucket{Burst: 20, Rate: 2}, settings.User)
+	assert.Equal(t, 5, settings.MuteAfter)
+
+	t.Setenv("RATE_LIMIT_USER", "5:0.5")
+	t.Setenv("RATE_LIMIT_ROOM", "0")
+	t.Setenv("RATE_LIMIT_CONNECTION", "bogus")
+	t.Setenv("RATE_LIMIT_MUTE_AFTER", "0")
+	t.Setenv("RATE_LIMIT_MUTE_DURATION", "60")
+
+	settings = config.LoadRateLimitSettings()
+	assert.Equal(t, ratelimit.Bucket{Burst: 5, Rate: 0.5}, settings.User)
+	assert.False(t, settings.Room.Enabled())
+	assert.Equal(t, ratelimit.Bucket{Burst: 10, Rate: 1}, settings.Connection) // 格式錯誤時保留預設值
+	assert.Equal(t, 0, settings.MuteAfter)
+	assert.Equal(t, time.Minute, settings.MuteDuration)
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 用戶的全域角色，與房間內的 owner、moderator 無關
+const (
+	RoleAdmin     = "admin"     // 管理詞庫、角色與所有房間
+	RoleModerator = "moderator" // 可審核與處分所有房間的用戶
+	RoleMember    = "member"    // 一般用戶，註冊時的預設角色
+	RoleBot       = "bot"       // 機器人帳號，例如讀取 /metrics 的監控
+)
+
+// AllRoles 所有角色
+var AllRoles = []string{RoleAdmin, RoleModerator, RoleMember, RoleBot}
+
+var (
+	ErrInvalidRole  = errors.New("invalid role")
+	ErrUserNotFound = errors.New("user not found")
+	ErrLastAdmin    = errors.New("cannot revoke the last admin")
+	ErrMemberRole   = errors.New("the member role cannot be changed")
+)
+
+// ParseRole 讀取角色名稱，不分大小寫
+func ParseRole(value string) (string, error) {
+	role := strings.ToLower(strings.TrimSpace(value))
+	if !slices.Contains(AllRoles, role) {
+		return "", fmt.Errorf("%w %q", ErrInvalidRole, value)
+	}
+	return role, nil
+}
+
+// UserRoles 讀取用戶的角色，用戶不存在時返回 ErrUserNotFound
+func UserRoles(username string) ([]string, error) {
+	var roles []string
+	err := PgConn.QueryRow(Ctx, "SELECT roles FROM users WHERE username = $1", username).Scan(&roles)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	return roles, err
+}
+
+// roleCacheTTL 角色快取的時間，授予或撤銷角色時會直接清除快取
+const roleCacheTTL = time.Minute
+
+func roleCacheKey(username string) string {
+	return "roles:" + username
+}
+
+// CurrentRoles 讀取用戶目前的角色，先讀 Redis 快取，沒有快取時查詢資料庫；用戶不存在時沒有任何角色。
+// 權限檢查以此為準而不是 JWT 中的角色，角色變更後不需要重新登入
+func CurrentRoles(username string) ([]string, error) {
+	cached, err := RedisClient.Get(Ctx, roleCacheKey(username)).Result()
+	if err == nil {
+		var roles []string
+		if err := json.Unmarshal([]byte(cached), &roles); err == nil {
+			return roles, nil
+		}
+	} else if err != redis.Nil {
+		Logger.Error("Error reading role cache:", err)
+	}
+
+	roles, err := UserRoles(username)
+	if errors.Is(err, ErrUserNotFound) {
+		roles, err = []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, _ := json.Marshal(roles)
+	if err := RedisClient.Set(Ctx, roleCacheKey(username), data, roleCacheTTL).Err(); err != nil {
+		Logger.Error("Error caching roles:", err)
+	}
+	return roles, nil
+}
+
+// invalidateRoles 清除角色快取，所有實例的下一個請求都會讀到新的角色
+func invalidateRoles(username string) {
+	if err := RedisClient.Del(Ctx, roleCacheKey(username)).Err(); err != nil {
+		Logger.Error("Error clearing role cache:", err)
+	}
+}
+
+// GrantRole 授予用戶角色，已有該角色時不變更；返回變更前後的角色
+func GrantRole(username, role string) (before, after []string, err error) {
+	if role == RoleMember {
+		return nil, nil, ErrMemberRole
+	}
+	if before, err = UserRoles(username); err != nil {
+		return nil, nil, err
+	}
+	err = PgConn.QueryRow(Ctx, `
+		UPDATE users SET roles = CASE WHEN $2 = ANY(roles) THEN roles ELSE array_append(roles, $2) END
+		WHERE username = $1
+		RETURNING roles`, username, role).Scan(&after)
+	if err != nil {
+		return nil, nil, err
+	}
+	invalidateRoles(username)
+	Logger.Infof("Granted role %s to %s", role, username)
+	return before, after, nil
+}
+
+// RevokeRole 撤銷用戶角色，不能撤銷最後一位管理員；返回變更前後的角色
+func RevokeRole(username, role string) (before, after []string, err error) {
+	if role == RoleMember {
+		return nil, nil, ErrMemberRole
+	}
+
+	tx, err := PgConn.Begin(Ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(Ctx)
+
+	// 鎖住所有管理員，避免同時撤銷兩位管理員時都通過檢查
+	if role == RoleAdmin {
+		rows, err := tx.Query(Ctx, "SELECT username FROM users WHERE $1 = ANY(roles) FOR UPDATE", RoleAdmin)
+		if err != nil {
+			return nil, nil, err
+		}
+		admins, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(admins) == 1 && admins[0] == username {
+			return nil, nil, ErrLastAdmin
+		}
+	}
+
+	err = tx.QueryRow(Ctx, "SELECT roles FROM users WHERE username = $1 FOR UPDATE", username).Scan(&before)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	err = tx.QueryRow(Ctx, "UPDATE users SET roles = array_remove(roles, $2) WHERE username = $1 RETURNING roles", username, role).Scan(&after)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(Ctx); err != nil {
+		return nil, nil, err
+	}
+	invalidateRoles(username)
+	Logger.Infof("Revoked role %s from %s", role, username)
+	return before, after, nil
+}
+
+// BootstrapAdmin 依 ADMIN_USERNAME 與 ADMIN_PASSWORD 建立第一位管理員：
+// 用戶已存在時授予 admin 角色（不變更密碼），不存在時以 ADMIN_PASSWORD 建立帳號。
+// 未設定時只在沒有任何管理員時提出警告
+func BootstrapAdmin() error {
+	username := strings.TrimSpace(os.Getenv("ADMIN_USERNAME"))
+	if username == "" {
+		var exists bool
+		if err := PgConn.QueryRow(Ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE $1 = ANY(roles))", RoleAdmin).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			Logger.Warn("No admin user exists; set ADMIN_USERNAME and ADMIN_PASSWORD to create one")
+		}
+		return nil
+	}
+
+	roles, err := UserRoles(username)
+	if errors.Is(err, ErrUserNotFound) {
+		password := os.Getenv("ADMIN_PASSWORD")
+		if password == "" {
+			return fmt.Errorf("ADMIN_PASSWORD is required to create admin user %s", username)
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		roles = []string{RoleMember, RoleAdmin}
+		if _, err := PgConn.Exec(Ctx, "INSERT INTO users (username, password, roles) VALUES ($1, $2, $3)", username, hash, roles); err != nil {
+			return err
+		}
+		invalidateRoles(username)
+		Logger.Infof("Created admin user %s", username)
+		recordRoleBootstrap(username, nil, roles)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if slices.Contains(roles, RoleAdmin) {
+		return nil
+	}
+
+	before, after, err := GrantRole(username, RoleAdmin)
+	if err != nil {
+		return err
+	}
+	recordRoleBootstrap(username, before, after)
+	return nil
+}
+
+// recordRoleBootstrap 記錄啟動時授予管理員角色的稽核紀錄
+func recordRoleBootstrap(username string, before, after []string) {
+	entry := AuditEntry{Actor: "system", Action: "role.grant", TargetType: "user", Target: username, Reason: "ADMIN_USERNAME"}
+	if before != nil {
+		entry.Before, _ = json.Marshal(before)
+	}
+	entry.After, _ = json.Marshal(after)
+	if err := RecordAudit(&entry); err != nil {
+		Logger.Error("Error recording audit entry:", err)
+	}
+}
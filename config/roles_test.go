@@ -0,0 +1,21 @@
+package config_test
+
+import (
+	"testing"
+
+	"example.com/m/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRole(t *testing.T) {
+	for _, value := range []string{"admin", " Moderator ", "MEMBER", "bot"} {
+		role, err := config.ParseRole(value)
+		assert.NoError(t, err, value)
+		assert.Contains(t, config.AllRoles, role)
+	}
+
+	for _, value := range []string{"", "owner", "root"} {
+		_, err := config.ParseRole(value)
+		assert.ErrorIs(t, err, config.ErrInvalidRole, value)
+	}
+}
@@ -1,293 +1,167 @@
 package config
 
 import (
+	"container/list"
+	"encoding/json"
 	"fmt"
 	"log"
-	"regexp"
-	"strings"
+	"os"
+	"slices"
+	"sync"
 
-	"github.com/360EntSecGroup-Skylar/excelize"
+	"example.com/m/filter"
 )
 
-var sensitiveWords []string
-
-// Aho-Corasick狀態機結構
-type AhoCorasick struct {
-	root     *Node
-	patterns []string
-}
-
-// Node表示Aho-Corasick中的一個節點
-type Node struct {
-	children map[rune]*Node
-	fail     *Node
-	output   []string
-}
-
-// 新建Aho-Corasick
-func NewAhoCorasick() *AhoCorasick {
-	return &AhoCorasick{root: &Node{children: make(map[rune]*Node)}}
-}
-
-// 插入敏感詞
-func (ac *AhoCorasick) Insert(pattern string) {
-	node := ac.root
-	for _, char := range pattern {
-		if _, ok := node.children[char]; !ok {
-			node.children[char] = &Node{children: make(map[rune]*Node)}
-		}
-		node = node.children[char]
-	}
-	node.output = append(node.output, pattern)
-}
-
-// 建立失敗指標
-func (ac *AhoCorasick) Build() {
-	queue := []*Node{ac.root}
-	for len(queue) > 0 {
-		current := queue[0]
-		queue = queue[1:]
-
-		for char, child := range current.children {
-			// 設置失敗指標
-			failNode := current.fail
-			for failNode != nil {
-				if next, ok := failNode.children[char]; ok {
-					child.fail = next
-					break
-				}
-				failNode = failNode.fail
-			}
-			if child.fail == nil {
-				child.fail = ac.root
-			}
-			child.output = append(child.output, child.fail.output...)
-			queue = append(queue, child)
-		}
-	}
-}
-
-// 用Aho-Corasick過濾消息
-func (ac *AhoCorasick) Filter(content string) map[string]int {
-	node := ac.root
-	results := make(map[string]int)
-
-	for _, char := range content {
-		for node != ac.root && node.children[char] == nil {
-			node = node.fail
-		}
-		node = node.children[char]
-
-		if node == nil {
-			node = ac.root
-		}
-
-		for _, pattern := range node.output {
-			results[pattern]++
-		}
-	}
-
-	return results
-}
-
-// 敏感詞初始化函數：從 PostgreSQL 加載敏感詞到 Redis
-func loadSensitiveWords() error {
-	// 清空 Redis 中舊的敏感詞
-	err := RedisClient.Del(Ctx, "sensitive_words").Err()
-	if err != nil {
-		return err
-	}
-
+// 敏感詞初始化函數：從 PostgreSQL 加載敏感詞到 Redis，並返回所有敏感詞及其分類資訊
+func loadSensitiveWords() ([]filter.Entry, error) {
 	// 從 PostgreSQL 中獲取所有敏感詞
-	rows, err := PgConn.Query(Ctx, "SELECT word FROM sensitive_words")
+	rows, err := PgConn.Query(Ctx, "SELECT word, COALESCE(category, ''), COALESCE(severity, 0), COALESCE(action, '') FROM sensitive_words ORDER BY id")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer rows.Close()
 
-	// 將敏感詞加載到 Redis
+	var entries []filter.Entry
+	var words []string
 	for rows.Next() {
-		var word string
-		if err := rows.Scan(&word); err != nil {
-			return err
+		var entry filter.Entry
+		if err := rows.Scan(&entry.Word, &entry.Category, &entry.Severity, &entry.Action); err != nil {
+			return nil, err
 		}
-		sensitiveWords = append(sensitiveWords, word)
-		err = RedisClient.SAdd(Ctx, "sensitive_words", word).Err()
-		if err != nil {
-			return err
+		entries = append(entries, entry)
+		words = append(words, entry.Word)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 以 pipeline 重建 Redis 中的敏感詞集合
+	pipe := RedisClient.TxPipeline()
+	pipe.Del(Ctx, "sensitive_words")
+	for i := 0; i < len(words); i += 1000 {
+		batch := make([]interface{}, 0, 1000)
+		for _, word := range words[i:min(i+1000, len(words))] {
+			batch = append(batch, word)
 		}
+		pipe.SAdd(Ctx, "sensitive_words", batch...)
+	}
+	if _, err := pipe.Exec(Ctx); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return entries, nil
 }
 
-// CheckForSplitSensitiveWords 检查是否有拆字的敏感词
-func CheckForSplitSensitiveWords(message string) map[string]int {
-	results := make(map[string]int)
+// 在主函數中初始化資料庫連接，Redis 連接，並處理敏感詞
 
-	for _, word := range sensitiveWords {
-		// 构建拆字模式
-		splitPattern := ""
-		for _, char := range word {
-			splitPattern += string(char) + ".*?" // 在字符之间插入正则表达式的“.*?”以匹配任意字符
-		}
+func InitSensitiveWordHandler() error {
 
-		// 使用正则表达式进行匹配
-		re := regexp.MustCompile(splitPattern)
-		if re.MatchString(message) {
-			results[word]++
+	// 從 Excel 文件匯入敏感詞，檔案未變更時會跳過
+	result, err := ImportSensitiveWordsFile(SensitiveWordsFile(), SensitiveWordsImportOptions(), os.Getenv("SENSITIVE_WORDS_FORCE_IMPORT") == "true")
+	if err != nil {
+		log.Fatalf("Error loading sensitive words from Excel: %v", err)
+	}
+	if result.Skipped {
+		log.Printf("Sensitive word file unchanged (checksum %s), import skipped", result.Checksum)
+	} else {
+		log.Printf("Imported sensitive words: %d added, %d removed", result.Added, result.Removed)
+		after, _ := json.Marshal(result)
+		entry := AuditEntry{Actor: "system", Action: "dictionary.sync", TargetType: "dictionary", Target: SensitiveWordsFile(), After: after}
+		if err := RecordAudit(&entry); err != nil {
+			log.Println("Error recording audit entry:", err)
 		}
 	}
-	return results
-}
 
-// 更新敏感詞列表並重新加載 Redis
-func addSensitiveWord(word string) error {
-	// 插入新敏感詞到 PostgreSQL
-	_, err := PgConn.Exec(Ctx, "INSERT INTO sensitive_words (word) VALUES ($1) ON CONFLICT DO NOTHING", word)
-	if err != nil {
+	// 初始化時加載敏感詞並建立詞庫
+	if err := ReloadSensitiveWords(); err != nil {
+		fmt.Println("Error loading sensitive words:", err)
 		return err
 	}
 
-	// 將新詞加載到 Redis
-	err = RedisClient.SAdd(Ctx, "sensitive_words", word).Err()
-	if err != nil {
-		return err
-	}
+	// 訂閱其他實例的詞庫變更通知
+	go subscribeSensitiveWordChanges()
 
-	// 更新敏感詞列表
-	sensitiveWords = append(sensitiveWords, word)
 	return nil
 }
 
-// 從 Excel 文件讀取敏感詞並插入 PostgreSQL
-func loadSensitiveWordsFromExcel(filePath string) error {
-	// 清空舊的敏感詞
-	_, err := PgConn.Exec(Ctx, "DELETE FROM sensitive_words")
-	if err != nil {
-		return err
-	}
-
-	// 打開 Excel 文件
-	f, err := excelize.OpenFile(filePath)
-	if err != nil {
-		return err
-	}
-
-	// 讀取工作表中的所有行
-	rows := f.GetRows("Sheet1") // 根據實際工作表名稱修改
-
-	// 從第二行開始讀取（跳過標題行）
-	for i, row := range rows {
-		if i == 0 { // 跳過第一行
-			continue
-		}
+// 过滤消息中的敏感词（包含拆字），返回依敏感词分类决定的处理结果
+func FilterMessage(message string) filter.Verdict {
+	return FilterMessageForRoom("", message, nil)
+}
 
-		// 遍歷行中的每個詞
-		for _, word := range row {
-			if word != "" { // 確保詞不為空
-				// 插入敏感詞到 PostgreSQL
-				_, err := PgConn.Exec(Ctx, "INSERT INTO sensitive_words (word) VALUES ($1) ON CONFLICT DO NOTHING", word)
-				if err != nil {
-					return err
-				}
-				// 將新詞加載到 Redis
-				err = RedisClient.SAdd(Ctx, "sensitive_words", word).Err()
-				if err != nil {
-					return err
-				}
-				// 更新敏感詞列表
-				sensitiveWords = append(sensitiveWords, word)
-			}
+// FilterMessageForRoom 以目前的词库依房间的过滤设定过滤消息，policy 为 nil 时只使用全域词库；
+// room 不为空时房间专属的敏感词只在第一次使用或变更后才重新建立词库
+func FilterMessageForRoom(room, message string, policy *filter.Policy) filter.Verdict {
+	dictionary := Ac.Load()
+	verdict := dictionary.CheckWithRoom(message, policy, roomDictionary(room, dictionary, policy))
+	for _, hit := range verdict.Hits {
+		if hit.Split {
+			log.Printf("检测到拆字敏感词: %s\n", hit.Word)
 		}
 	}
-
-	return nil
+	return verdict
 }
 
-// 在主函數中初始化資料庫連接，Redis 連接，並處理敏感詞
+// compiledRoomWords 快取的房间词库，记录建立时使用的全域词库与房间词
+type compiledRoomWords struct {
+	room       string
+	base       *filter.Dictionary
+	words      []filter.Entry
+	dictionary *filter.Dictionary
+}
 
-func InitSensitiveWordHandler() (*AhoCorasick, error) {
+// maxRoomDictionaries 最多快取的房间词库数量，超过时移除最久未使用的房间
+const maxRoomDictionaries = 256
 
-	// 從 Excel 文件加載敏感詞
-	err := loadSensitiveWordsFromExcel("./combined_sensitive_words.xlsx") // 替換為您的文件路徑
-	if err != nil {
-		log.Fatalf("Error loading sensitive words from Excel: %v", err)
-	}
+var (
+	roomDictionariesMu  sync.Mutex
+	roomDictionaries    = map[string]*list.Element{} // 房间名称 → roomDictionaryOrder 中的 *compiledRoomWords
+	roomDictionaryOrder = list.New()                 // 最近使用的在前
+)
 
-	// 初始化時加載敏感詞
-	err = loadSensitiveWords()
-	if err != nil {
-		fmt.Println("Error loading sensitive words:", err)
-		return nil, err
+// roomDictionary 返回房间专属敏感词的词库。全域词库重新加载或房间词与快取不同时才重新建立，
+// 其他实例更新的房间设定也会在下一则消息生效。只快取有房间专属敏感词的房间，最多 maxRoomDictionaries 个
+func roomDictionary(room string, base *filter.Dictionary, policy *filter.Policy) *filter.Dictionary {
+	if room == "" || policy == nil || len(policy.Words) == 0 {
+		return base.RoomDictionary(policy)
 	}
 
-	// 建立 Aho-Corasick 機器並插入敏感詞
-	ac := NewAhoCorasick()
-	for _, word := range sensitiveWords {
-		ac.Insert(word)
+	roomDictionariesMu.Lock()
+	if element, ok := roomDictionaries[room]; ok {
+		compiled := element.Value.(*compiledRoomWords)
+		if compiled.base == base && slices.Equal(compiled.words, policy.Words) {
+			roomDictionaryOrder.MoveToFront(element)
+			roomDictionariesMu.Unlock()
+			return compiled.dictionary
+		}
 	}
-	ac.Build()
-
-	return ac, nil
-}
+	roomDictionariesMu.Unlock()
 
-// 模擬消息處理的函數，可以作為測試或使用者輸入的範例
-func SimulateMessageFiltering(message string) {
-	var ac *AhoCorasick
+	// 建立词库时不持有锁，避免阻塞其他房间的消息
+	compiled := &compiledRoomWords{room: room, base: base, words: slices.Clone(policy.Words), dictionary: base.RoomDictionary(policy)}
 
-	results := ac.Filter(message)
-	for word, count := range results {
-		fmt.Printf("檢測到敏感詞: %s (次數: %d)\n", word, count)
+	roomDictionariesMu.Lock()
+	defer roomDictionariesMu.Unlock()
+	if element, ok := roomDictionaries[room]; ok {
+		element.Value = compiled
+		roomDictionaryOrder.MoveToFront(element)
+	} else {
+		roomDictionaries[room] = roomDictionaryOrder.PushFront(compiled)
 	}
-
-	// 將檢測到的敏感詞替換為 *
-	filteredMessage := message
-	for word := range results {
-		replacement := strings.Repeat("*", len(word))
-		filteredMessage = strings.ReplaceAll(filteredMessage, word, replacement)
+	for roomDictionaryOrder.Len() > maxRoomDictionaries {
+		oldest := roomDictionaryOrder.Back()
+		roomDictionaryOrder.Remove(oldest)
+		delete(roomDictionaries, oldest.Value.(*compiledRoomWords).room)
 	}
-	fmt.Println("Filtered message:", filteredMessage)
+	return compiled.dictionary
 }
 
-// 过滤消息中的敏感词（包含拆字）
-func FilterMessage(message string) string {
-	// 使用 Aho-Corasick 检查完整的敏感词
-	results := Ac.Filter(message)
-
-	// 处理拆字的敏感词
-	splitResults := CheckForSplitSensitiveWords(message)
-	for word, count := range splitResults {
-		results[word] += count // 合并计数
-		log.Printf("检测到拆字敏感词: %s (次数: %d)\n", word, count)
-	}
-
-	// 将检测到的敏感词替换为 *
-	filteredMessage := message
-
-	// 用正则表达式匹配所有非字母数字字符（包括空格、标点符号等）
-	re := regexp.MustCompile(`[^a-zA-Z0-9\p{Han}]+`) // 使用 \p{Han} 匹配汉字
-	// 将非字母数字字符替换为一个空格，以便正确拆分
-	normalizedMessage := re.ReplaceAllString(filteredMessage, " ")
-	fmt.Println(normalizedMessage)
-
-	// 用空格拆分消息
-	parts := strings.Fields(normalizedMessage)
-
-	// 组合成一个新的字符串
-	filteredMessage = strings.Join(parts, " ")
-
-	for word, _ := range results {
-		replacement := strings.Repeat("*", len(word))
-		filteredMessage = strings.ReplaceAll(filteredMessage, word, replacement)
+// InvalidateRoomDictionary 房间过滤设定更新后移除快取的房间词库
+func InvalidateRoomDictionary(room string) {
+	roomDictionariesMu.Lock()
+	defer roomDictionariesMu.Unlock()
+	if element, ok := roomDictionaries[room]; ok {
+		roomDictionaryOrder.Remove(element)
+		delete(roomDictionaries, room)
 	}
-
-	// 记录完整的敏感词检测结果
-	// for word, count := range results {
-	// 	if count > 0 {
-	// 		log.Printf("检测到敏感词: %s (次数: %d)\n", word, count)
-	// 	}
-	// }
-
-	return filteredMessage
 }
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"example.com/m/filter"
+)
+
+// SensitiveWordsCacheFile 編譯後詞庫的快取檔案路徑，SENSITIVE_WORDS_CACHE 設為空字串時不使用快取
+func SensitiveWordsCacheFile() string {
+	if path, ok := os.LookupEnv("SENSITIVE_WORDS_CACHE"); ok {
+		return path
+	}
+	return "./sensitive_words.dict"
+}
+
+// DictionaryFingerprint 以敏感詞、分類資訊與允許詞計算詞庫內容的 SHA-256（hex），
+// 匯入檔案、管理員新增的詞與允許詞任何一項變更都會得到不同的值
+func DictionaryFingerprint(words []filter.Entry, terms []string) string {
+	h := sha256.New()
+	for _, word := range words {
+		fmt.Fprintf(h, "word\x00%s\x00%s\x00%d\x00%s\n", word.Word, word.Category, word.Severity, word.Action)
+	}
+	for _, term := range terms {
+		fmt.Fprintf(h, "allow\x00%s\n", term)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ReadDictionaryCache 讀取 WriteDictionaryCache 寫入的詞庫。檔案不存在或指紋與 fingerprint 不同時返回 nil, nil；
+// 正規化設定或檔案格式與目前版本不符時返回錯誤，呼叫端應重新建立詞庫
+func ReadDictionaryCache(path, fingerprint string, options filter.Options) (*filter.Dictionary, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	header, err := r.ReadString('\n')
+	if err != nil || strings.TrimSpace(header) != fingerprint {
+		return nil, nil
+	}
+	return filter.ReadDictionary(r, options)
+}
+
+// WriteDictionaryCache 將詞庫與其指紋寫入快取檔案，先寫入暫存檔再改名，讀取時不會看到寫到一半的檔案
+func WriteDictionaryCache(path, fingerprint string, dictionary *filter.Dictionary) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if _, err := w.WriteString(fingerprint + "\n"); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := dictionary.WriteTo(w); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// loadDictionary 讀取與目前內容相同的快取詞庫，沒有可用的快取時重新建立並寫入快取
+func loadDictionary(words []filter.Entry, terms []string) (*filter.Dictionary, bool) {
+	path := SensitiveWordsCacheFile()
+	if path == "" {
+		return filter.NewDictionary(words, terms, FilterSettings), false
+	}
+
+	fingerprint := DictionaryFingerprint(words, terms)
+	dictionary, err := ReadDictionaryCache(path, fingerprint, FilterSettings)
+	if err != nil {
+		log.Println("Error reading cached sensitive word dictionary, rebuilding:", err)
+	}
+	if dictionary != nil {
+		return dictionary, true
+	}
+
+	dictionary = filter.NewDictionary(words, terms, FilterSettings)
+	if err := WriteDictionaryCache(path, fingerprint, dictionary); err != nil {
+		log.Println("Error writing sensitive word dictionary cache:", err)
+	}
+	return dictionary, false
+}
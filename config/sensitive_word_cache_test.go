@@ -0,0 +1,57 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"example.com/m/config"
+	"example.com/m/filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDictionaryCache(t *testing.T) {
+	words := []filter.Entry{{Word: "damn"}, {Word: "vote", Category: filter.CategoryPolitical}}
+	terms := []string{"damnation"}
+	fingerprint := config.DictionaryFingerprint(words, terms)
+	path := filepath.Join(t.TempDir(), "sensitive_words.dict")
+
+	dictionary, err := config.ReadDictionaryCache(path, fingerprint, filter.DefaultOptions())
+	require.NoError(t, err)
+	assert.Nil(t, dictionary, "missing cache file")
+
+	built := filter.NewDictionary(words, terms, filter.DefaultOptions())
+	require.NoError(t, config.WriteDictionaryCache(path, fingerprint, built))
+
+	dictionary, err = config.ReadDictionaryCache(path, fingerprint, filter.DefaultOptions())
+	require.NoError(t, err)
+	require.NotNil(t, dictionary)
+	for _, message := range []string{"damn it", "damnation", "vote now"} {
+		assert.Equal(t, built.Check(message, nil), dictionary.Check(message, nil), message)
+	}
+
+	// 詞庫內容變更後指紋不同，快取不再使用
+	changed := config.DictionaryFingerprint(append(words, filter.Entry{Word: "jira"}), terms)
+	assert.NotEqual(t, fingerprint, changed)
+	dictionary, err = config.ReadDictionaryCache(path, changed, filter.DefaultOptions())
+	require.NoError(t, err)
+	assert.Nil(t, dictionary)
+
+	// 正規化設定不同時返回錯誤，呼叫端會重新建立
+	options := filter.DefaultOptions()
+	options.Normalize = filter.NormalizeCaseFold
+	_, err = config.ReadDictionaryCache(path, fingerprint, options)
+	assert.Error(t, err)
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary file removed after rename")
+}
+
+func TestDictionaryFingerprintCoversAllowList(t *testing.T) {
+	words := []filter.Entry{{Word: "damn"}}
+	assert.Equal(t, config.DictionaryFingerprint(words, nil), config.DictionaryFingerprint(words, []string{}))
+	assert.NotEqual(t, config.DictionaryFingerprint(words, nil), config.DictionaryFingerprint(words, []string{"damnation"}))
+	assert.NotEqual(t, config.DictionaryFingerprint(words, nil), config.DictionaryFingerprint([]filter.Entry{{Word: "damn", Severity: 3}}, nil))
+}
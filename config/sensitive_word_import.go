@@ -0,0 +1,205 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"example.com/m/filter"
+	"example.com/m/filter/importer"
+	"github.com/jackc/pgx/v5"
+)
+
+// 敏感詞來源
+const (
+	WordSourceExcel = "excel" // 啟動時從詞庫檔案匯入
+	WordSourceAdmin = "admin" // 管理員逐筆新增
+	WordSourceAPI   = "api"   // 透過匯入 API 批次新增
+)
+
+// errEmptyDictionary 匯入結果為空時返回，避免誤把整個詞庫刪除
+var errEmptyDictionary = errors.New("dictionary file contains no words")
+
+// ImportResult 詞庫檔案匯入的結果
+type ImportResult struct {
+	Checksum string `json:"checksum"`
+	Words    int    `json:"words"`   // 檔案中的詞數
+	Added    int    `json:"added"`   // 新增的詞數
+	Updated  int    `json:"updated"` // 分類資訊有變更的詞數
+	Removed  int    `json:"removed"` // 檔案已移除、因此從資料庫刪除的詞數
+	Skipped  bool   `json:"skipped"` // 檔案未變更而跳過
+}
+
+// SensitiveWordsFile 返回詞庫檔案路徑，可用 SENSITIVE_WORDS_FILE 環境變數覆寫
+func SensitiveWordsFile() string {
+	if path := os.Getenv("SENSITIVE_WORDS_FILE"); path != "" {
+		return path
+	}
+	return "./combined_sensitive_words.xlsx"
+}
+
+// SensitiveWordsImportOptions 從環境變數讀取詞庫檔案的表格設定
+func SensitiveWordsImportOptions() importer.Options {
+	options := importer.DefaultOptions()
+	options.Sheet = os.Getenv("SENSITIVE_WORDS_SHEET")
+	if column, err := strconv.Atoi(os.Getenv("SENSITIVE_WORDS_COLUMN")); err == nil {
+		options.Column = column
+	}
+	if header := os.Getenv("SENSITIVE_WORDS_HEADER"); header != "" {
+		options.HasHeader = header == "true"
+	}
+	return options
+}
+
+// ImportSensitiveWordsFile 將詞庫檔案與資料庫比對後匯入：
+// 新詞以 COPY 寫入暫存表後一次 upsert，檔案中已不存在的 excel 來源詞會被刪除，
+// 管理員或 API 新增的詞不受影響。整個過程在單一交易內完成，失敗時資料庫保持原狀。
+// 檔案 checksum 與上次匯入相同且 force 為 false 時直接跳過。
+func ImportSensitiveWordsFile(filePath string, options importer.Options, force bool) (*ImportResult, error) {
+	checksum, err := fileChecksum(filePath)
+	if err != nil {
+		return nil, err
+	}
+	result := &ImportResult{Checksum: checksum}
+
+	if !force {
+		var lastChecksum string
+		err := PgConn.QueryRow(Ctx, "SELECT checksum FROM dictionary_imports WHERE file_path = $1 ORDER BY imported_at DESC LIMIT 1", filePath).Scan(&lastChecksum)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		if lastChecksum == checksum {
+			result.Skipped = true
+			return result, nil
+		}
+	}
+
+	// 先完整讀取檔案，避免讀到一半失敗時資料庫已被修改
+	wordImporter, err := importer.ForFile(filePath, options)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := wordImporter.Import(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	entries = normalizeEntries(entries)
+	if len(entries) == 0 {
+		return nil, errEmptyDictionary
+	}
+	result.Words = len(entries)
+
+	tx, err := PgConn.Begin(Ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(Ctx)
+
+	_, err = tx.Exec(Ctx, `CREATE TEMP TABLE import_words (
+		word VARCHAR(255) PRIMARY KEY,
+		category VARCHAR(20),
+		severity INTEGER,
+		action VARCHAR(20)
+	) ON COMMIT DROP`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([][]interface{}, len(entries))
+	for i, entry := range entries {
+		rows[i] = []interface{}{entry.Word, nullIfEmpty(entry.Category), nullIfZero(entry.Severity), nullIfEmpty(entry.Action)}
+	}
+	_, err = tx.CopyFrom(Ctx, pgx.Identifier{"import_words"}, []string{"word", "category", "severity", "action"}, pgx.CopyFromRows(rows))
+	if err != nil {
+		return nil, err
+	}
+
+	// 新詞直接新增；已存在的 excel 來源詞在分類資訊變更時更新，其他來源的詞保持不變
+	err = tx.QueryRow(Ctx, `
+		WITH upserted AS (
+			INSERT INTO sensitive_words (word, source, category, severity, action)
+			SELECT word, $1, category, severity, action FROM import_words
+			ON CONFLICT (word) DO UPDATE
+			SET category = EXCLUDED.category, severity = EXCLUDED.severity, action = EXCLUDED.action, updated_at = NOW()
+			WHERE sensitive_words.source = $1
+				AND (sensitive_words.category, sensitive_words.severity, sensitive_words.action)
+					IS DISTINCT FROM (EXCLUDED.category, EXCLUDED.severity, EXCLUDED.action)
+			RETURNING (xmax = 0) AS inserted
+		)
+		SELECT COUNT(*) FILTER (WHERE inserted), COUNT(*) FILTER (WHERE NOT inserted) FROM upserted`,
+		WordSourceExcel).Scan(&result.Added, &result.Updated)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := tx.Exec(Ctx, `
+		DELETE FROM sensitive_words s
+		WHERE s.source = $1 AND NOT EXISTS (SELECT 1 FROM import_words i WHERE i.word = s.word)`, WordSourceExcel)
+	if err != nil {
+		return nil, err
+	}
+	result.Removed = int(tag.RowsAffected())
+
+	_, err = tx.Exec(Ctx, "INSERT INTO dictionary_imports (file_path, checksum, word_count, added, removed) VALUES ($1, $2, $3, $4, $5)",
+		filePath, checksum, result.Words, result.Added, result.Removed)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(Ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// normalizeEntries 去除空白與重複的詞，重複時保留第一筆
+func normalizeEntries(entries []filter.Entry) []filter.Entry {
+	seen := make(map[string]bool)
+	result := make([]filter.Entry, 0, len(entries))
+	for _, entry := range entries {
+		entry.Word = strings.TrimSpace(entry.Word)
+		if entry.Word == "" || seen[entry.Word] {
+			continue
+		}
+		seen[entry.Word] = true
+		result = append(result, entry)
+	}
+	return result
+}
+
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func nullIfZero(value int) interface{} {
+	if value == 0 {
+		return nil
+	}
+	return value
+}
+
+func fileChecksum(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
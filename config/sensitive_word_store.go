@@ -0,0 +1,276 @@
+package config
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"sync"
+
+	"example.com/m/filter"
+	"github.com/jackc/pgx/v5"
+)
+
+// 詞庫變更通知的 Redis Pub/Sub 頻道，訊息內容為發出通知的實例 ID
+const sensitiveWordsChannel = "sensitive_words:changed"
+
+// reloadMu 確保同時只有一個重建在進行，避免舊的詞庫覆蓋新的詞庫
+var reloadMu sync.Mutex
+
+// ReloadSensitiveWords 從 PostgreSQL 重新讀取敏感詞與允許詞，建立新的詞庫後整體替換，
+// 重建期間進行中的過濾仍使用舊詞庫，不會停機
+func ReloadSensitiveWords() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	words, err := loadSensitiveWords()
+	if err != nil {
+		return err
+	}
+	terms, err := ListAllowTerms()
+	if err != nil {
+		return err
+	}
+
+	// 內容與上次建立時相同時直接讀取編譯好的詞庫，省去正規化與建立字典樹
+	dictionary, cached := loadDictionary(words, terms)
+	Ac.Store(dictionary)
+	Logger.Infof("Sensitive word dictionary reloaded with %d words and %d allow-list terms (from cache: %t)", len(words), len(terms), cached)
+	return nil
+}
+
+// AddSensitiveWordEntries 新增敏感詞與其分類資訊並記錄來源，返回實際新增的數量
+func AddSensitiveWordEntries(entries []filter.Entry, source string) (int, error) {
+	entries = normalizeEntries(entries)
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	for _, entry := range entries {
+		if err := entry.Validate(); err != nil {
+			return 0, err
+		}
+	}
+
+	words := make([]string, len(entries))
+	categories := make([]interface{}, len(entries))
+	severities := make([]interface{}, len(entries))
+	actions := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		words[i] = entry.Word
+		categories[i] = nullIfEmpty(entry.Category)
+		severities[i] = nullIfZero(entry.Severity)
+		actions[i] = nullIfEmpty(entry.Action)
+	}
+
+	tag, err := PgConn.Exec(Ctx, `
+		INSERT INTO sensitive_words (word, source, category, severity, action)
+		SELECT w, $2, c, s, a FROM unnest($1::text[], $3::text[], $4::int[], $5::text[]) AS t(w, c, s, a)
+		ON CONFLICT DO NOTHING`, words, source, categories, severities, actions)
+	if err != nil {
+		return 0, err
+	}
+
+	Logger.Infof("Added %d of %d sensitive words from %s", tag.RowsAffected(), len(entries), source)
+	return int(tag.RowsAffected()), NotifySensitiveWordsChanged()
+}
+
+// AddSensitiveWords 新增敏感詞並記錄來源，返回實際新增的數量
+func AddSensitiveWords(words []string, source string) (int, error) {
+	entries := make([]filter.Entry, len(words))
+	for i, word := range words {
+		entries[i] = filter.Entry{Word: word}
+	}
+	return AddSensitiveWordEntries(entries, source)
+}
+
+// AddSensitiveWord 新增單一敏感詞
+func AddSensitiveWord(word, source string) error {
+	_, err := AddSensitiveWords([]string{word}, source)
+	return err
+}
+
+// UpdateSensitiveWord 更新敏感詞的分類資訊，詞不存在時返回 false
+func UpdateSensitiveWord(entry filter.Entry) (bool, error) {
+	if err := entry.Validate(); err != nil {
+		return false, err
+	}
+
+	tag, err := PgConn.Exec(Ctx, "UPDATE sensitive_words SET category = $2, severity = $3, action = $4, updated_at = NOW() WHERE word = $1",
+		entry.Word, nullIfEmpty(entry.Category), nullIfZero(entry.Severity), nullIfEmpty(entry.Action))
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	Logger.Infof("Updated sensitive word %q", entry.Word)
+	return true, NotifySensitiveWordsChanged()
+}
+
+// RemoveSensitiveWords 刪除敏感詞，返回實際刪除的數量
+func RemoveSensitiveWords(words []string) (int, error) {
+	words = normalizeWords(words)
+	if len(words) == 0 {
+		return 0, nil
+	}
+
+	tag, err := PgConn.Exec(Ctx, "DELETE FROM sensitive_words WHERE word = ANY($1)", words)
+	if err != nil {
+		return 0, err
+	}
+
+	Logger.Infof("Removed %d sensitive words", tag.RowsAffected())
+	return int(tag.RowsAffected()), NotifySensitiveWordsChanged()
+}
+
+// SensitiveWord 詞庫中的敏感詞與其來源、分類資訊
+type SensitiveWord struct {
+	Word   string `json:"word"`
+	Source string `json:"source"`
+	filter.WordInfo
+}
+
+// GetSensitiveWord 讀取單一敏感詞，詞不存在時返回 nil
+func GetSensitiveWord(word string) (*SensitiveWord, error) {
+	var entry filter.Entry
+	var source string
+	err := PgConn.QueryRow(Ctx, `
+		SELECT word, source, COALESCE(category, ''), COALESCE(severity, 0), COALESCE(action, '')
+		FROM sensitive_words WHERE word = $1`, strings.TrimSpace(word)).
+		Scan(&entry.Word, &source, &entry.Category, &entry.Severity, &entry.Action)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SensitiveWord{Word: entry.Word, Source: source, WordInfo: entry.Info()}, nil
+}
+
+// ListSensitiveWords 分頁列出敏感詞，query 不為空時只列出包含該字串的詞
+func ListSensitiveWords(query string, limit, offset int) ([]SensitiveWord, int, error) {
+	var total int
+	pattern := "%" + escapeLike(query) + "%"
+	err := PgConn.QueryRow(Ctx, `SELECT COUNT(*) FROM sensitive_words WHERE word LIKE $1 ESCAPE '\'`, pattern).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := PgConn.Query(Ctx, `
+		SELECT word, source, COALESCE(category, ''), COALESCE(severity, 0), COALESCE(action, '')
+		FROM sensitive_words WHERE word LIKE $1 ESCAPE '\' ORDER BY word LIMIT $2 OFFSET $3`, pattern, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	words := []SensitiveWord{}
+	for rows.Next() {
+		var entry filter.Entry
+		var source string
+		if err := rows.Scan(&entry.Word, &source, &entry.Category, &entry.Severity, &entry.Action); err != nil {
+			return nil, 0, err
+		}
+		words = append(words, SensitiveWord{Word: entry.Word, Source: source, WordInfo: entry.Info()})
+	}
+	return words, total, rows.Err()
+}
+
+// escapeLike 跳脫 LIKE 的萬用字元，搭配 ESCAPE '\' 使用，使 % 與 _ 依字面比對
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// ListAllowTerms 列出所有允許詞
+func ListAllowTerms() ([]string, error) {
+	rows, err := PgConn.Query(Ctx, "SELECT term FROM sensitive_word_allowlist ORDER BY term")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	terms := []string{}
+	for rows.Next() {
+		var term string
+		if err := rows.Scan(&term); err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	return terms, rows.Err()
+}
+
+// AddAllowTerms 新增允許詞，返回實際新增的數量
+func AddAllowTerms(terms []string, addedBy string) (int, error) {
+	terms = normalizeWords(terms)
+	if len(terms) == 0 {
+		return 0, nil
+	}
+
+	tag, err := PgConn.Exec(Ctx, `
+		INSERT INTO sensitive_word_allowlist (term, added_by)
+		SELECT t, $2 FROM unnest($1::text[]) AS t
+		ON CONFLICT DO NOTHING`, terms, addedBy)
+	if err != nil {
+		return 0, err
+	}
+
+	Logger.Infof("%s added %d allow-list terms", addedBy, tag.RowsAffected())
+	return int(tag.RowsAffected()), NotifySensitiveWordsChanged()
+}
+
+// RemoveAllowTerms 刪除允許詞，返回實際刪除的數量
+func RemoveAllowTerms(terms []string) (int, error) {
+	terms = normalizeWords(terms)
+	if len(terms) == 0 {
+		return 0, nil
+	}
+
+	tag, err := PgConn.Exec(Ctx, "DELETE FROM sensitive_word_allowlist WHERE term = ANY($1)", terms)
+	if err != nil {
+		return 0, err
+	}
+
+	Logger.Infof("Removed %d allow-list terms", tag.RowsAffected())
+	return int(tag.RowsAffected()), NotifySensitiveWordsChanged()
+}
+
+// NotifySensitiveWordsChanged 重建本機詞庫並通知其他實例重新加載
+func NotifySensitiveWordsChanged() error {
+	if err := ReloadSensitiveWords(); err != nil {
+		return err
+	}
+	return RedisClient.Publish(Ctx, sensitiveWordsChannel, InstanceID).Err()
+}
+
+// subscribeSensitiveWordChanges 訂閱詞庫變更通知，收到其他實例的通知時重新加載
+func subscribeSensitiveWordChanges() {
+	pubsub := RedisClient.Subscribe(Ctx, sensitiveWordsChannel)
+	defer pubsub.Close()
+
+	for msg := range pubsub.Channel() {
+		if msg.Payload == InstanceID {
+			continue // 自己發出的通知，本機已經重建過
+		}
+		if err := ReloadSensitiveWords(); err != nil {
+			log.Println("Error reloading sensitive words:", err)
+		}
+	}
+}
+
+// normalizeWords 去除空白與重複的詞
+func normalizeWords(words []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if word == "" || seen[word] {
+			continue
+		}
+		seen[word] = true
+		result = append(result, word)
+	}
+	return result
+}
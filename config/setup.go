@@ -2,13 +2,22 @@ package config
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"example.com/m/filter"
 	"example.com/m/metrics"
+	"example.com/m/moderation"
+	"example.com/m/ratelimit"
+	"example.com/m/spam"
+	"example.com/m/storage"
 	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/websocket"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -17,18 +26,28 @@ import (
 )
 
 var (
-	RedisClient *redis.Client
-	PgConn      *pgxpool.Pool
-	Ctx         = context.Background()
-	Upgrader    = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
-	Clients     = make(map[*websocket.Conn]string)
-	SessionTTL  = 10 * time.Minute
-	Mu          sync.Mutex
-	Logger      = logrus.New()
-	AuthKey     = "YOUR_GENERATED_AUTH_KEY"
-	SecretKey   = "YOUR_GENERATED_SECRET_KEY"
-	Log         *logrus.Logger
-	Ac          *AhoCorasick
+	RedisClient    *redis.Client
+	PgConn         *pgxpool.Pool
+	Ctx            = context.Background()
+	Upgrader       = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+	Clients        = make(map[*websocket.Conn]string)
+	SessionTTL     = 10 * time.Minute
+	Mu             sync.Mutex
+	Logger         = logrus.New()
+	AuthKey        = "YOUR_GENERATED_AUTH_KEY"
+	SecretKey      = "YOUR_GENERATED_SECRET_KEY"
+	Log            *logrus.Logger
+	Ac             atomic.Pointer[filter.Dictionary] // 敏感詞詞庫，詞庫變更時整體替換
+	InstanceID     = newInstanceID()
+	Uploads        UploadSettings
+	Moderation     ModerationSettings
+	ModerationHook *moderation.Hook // 外部內容審核服務，未設定時為 nil
+	RateLimits     RateLimitSettings
+	RateLimiter    ratelimit.Limiter // WebSocket 訊息的頻率限制，令牌桶存放在 Redis
+	Spam           SpamSettings
+	SpamChain      *spam.Chain // 垃圾訊息評分規則，重複訊息的紀錄存放在 Redis
+	FileStorage    storage.Storage
+	MetricsToken   string // Prometheus 抓取 /metrics 的固定憑證，為空時只接受 JWT
 
 	// Prometheus metrics
 	RegisterUserCounter = prometheus.NewCounterVec(
@@ -52,6 +71,14 @@ var (
 	}, []string{"room"})
 )
 
+// newInstanceID 產生目前實例的識別碼，用於區分 Pub/Sub 通知的來源
+func newInstanceID() string {
+	host, _ := os.Hostname()
+	buf := make([]byte, 4)
+	rand.Read(buf)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(buf))
+}
+
 func Init() {
 	var err error
 	// 初始化 Redis 客戶端
@@ -70,14 +97,36 @@ func Init() {
 		log.Fatalf("Error checking/creating chat table: %v", err)
 	}
 
-	// 初始化敏感詞處理邏輯
-	Ac, err = InitSensitiveWordHandler()
+	// 依 ADMIN_USERNAME 建立或授予第一位管理員
+	if err := BootstrapAdmin(); err != nil {
+		log.Fatalf("Error bootstrapping admin user: %v", err)
+	}
+
+	// 初始化附件存放
+	Uploads = LoadUploadSettings()
+	FileStorage, err = storage.NewLocalStorage(Uploads.Dir)
 	if err != nil {
+		log.Fatalf("Failed to initialize file storage: %v", err)
+	}
+
+	Moderation = LoadModerationSettings()
+	ModerationHook = LoadModerationHook()
+	RateLimits = LoadRateLimitSettings()
+	RateLimiter = ratelimit.NewRedisLimiter(RedisClient, "ratelimit:")
+	Spam = LoadSpamSettings()
+	SpamChain = Spam.Chain(spam.NewRedisHistory(RedisClient, "spam:duplicate:"))
+
+	// 初始化敏感詞處理邏輯
+	if err := InitSensitiveWordHandler(); err != nil {
 		log.Fatalf("Error initializing sensitive word handler: %v", err)
 	}
 
 	// 初始化 Prometheus 监控
 	metrics.InitMetrics()
+	MetricsToken, err = LoadMetricsToken()
+	if err != nil {
+		log.Fatalf("Failed to load metrics token: %v", err)
+	}
 
 	// 註冊 Prometheus 指標
 	prometheus.MustRegister(RegisterUserCounter)
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"example.com/m/spam"
+)
+
+// SpamSettings 垃圾訊息偵測的設定
+type SpamSettings struct {
+	HoldScore   float64         // 總分達到此值時送交人工審核，0 表示不送審
+	RejectScore float64         // 總分達到此值時拒絕訊息，0 表示不拒絕
+	Rules       map[string]bool // 啟用的規則
+
+	DuplicateWindow     time.Duration // 判斷重複訊息的時間範圍
+	DuplicateMaxRepeats int           // 同一則訊息在時間範圍內允許的發送次數
+	MaxLinks            int           // 一則訊息允許的連結數
+	NewAccountAge       time.Duration // 註冊未滿此時間的帳號視為新帳號
+	NewAccountMaxLinks  int           // 新帳號一則訊息允許的連結數
+	MaxMentions         int           // 一則訊息允許提及的用戶數
+}
+
+// LoadSpamSettings 從環境變數讀取垃圾訊息偵測設定：
+//
+//	SPAM_RULES                 啟用的規則，例如 "duplicate,links"，預設全部啟用
+//	SPAM_HOLD_SCORE            預設 5
+//	SPAM_REJECT_SCORE          預設 10
+//	SPAM_DUPLICATE_WINDOW      秒，預設 60
+//	SPAM_DUPLICATE_MAX_REPEATS 預設 2
+//	SPAM_MAX_LINKS             預設 3
+//	SPAM_NEW_ACCOUNT_AGE       秒，預設 86400
+//	SPAM_NEW_ACCOUNT_MAX_LINKS 預設 0
+//	SPAM_MAX_MENTIONS          預設 5
+func LoadSpamSettings() SpamSettings {
+	settings := SpamSettings{
+		HoldScore:           5,
+		RejectScore:         10,
+		Rules:               make(map[string]bool),
+		DuplicateWindow:     time.Minute,
+		DuplicateMaxRepeats: 2,
+		MaxLinks:            3,
+		NewAccountAge:       24 * time.Hour,
+		NewAccountMaxLinks:  0,
+		MaxMentions:         5,
+	}
+	for _, rule := range spam.AllRules {
+		settings.Rules[rule] = true
+	}
+
+	if value, ok := os.LookupEnv("SPAM_RULES"); ok {
+		if rules, err := spam.ParseRules(value); err == nil {
+			settings.Rules = rules
+		} else {
+			Logger.Warnf("Ignoring SPAM_RULES: %v", err)
+		}
+	}
+
+	if v, err := strconv.ParseFloat(os.Getenv("SPAM_HOLD_SCORE"), 64); err == nil && v >= 0 {
+		settings.HoldScore = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("SPAM_REJECT_SCORE"), 64); err == nil && v >= 0 {
+		settings.RejectScore = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("SPAM_DUPLICATE_WINDOW")); err == nil && v > 0 {
+		settings.DuplicateWindow = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("SPAM_DUPLICATE_MAX_REPEATS")); err == nil && v >= 1 {
+		settings.DuplicateMaxRepeats = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("SPAM_MAX_LINKS")); err == nil && v >= 0 {
+		settings.MaxLinks = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("SPAM_NEW_ACCOUNT_AGE")); err == nil && v >= 0 {
+		settings.NewAccountAge = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("SPAM_NEW_ACCOUNT_MAX_LINKS")); err == nil && v >= 0 {
+		settings.NewAccountMaxLinks = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("SPAM_MAX_MENTIONS")); err == nil && v >= 0 {
+		settings.MaxMentions = v
+	}
+
+	return settings
+}
+
+// Chain 依設定建立評分規則鏈，history 用於偵測重複訊息
+func (s SpamSettings) Chain(history spam.History) *spam.Chain {
+	scorers := []spam.SpamScorer{
+		&spam.DuplicateScorer{History: history, Window: s.DuplicateWindow, MaxRepeats: s.DuplicateMaxRepeats, MinLength: 5, Weight: 3},
+		&spam.LinkScorer{MaxLinks: s.MaxLinks, NewAccountAge: s.NewAccountAge, NewAccountMaxLinks: s.NewAccountMaxLinks, Weight: 2},
+		&spam.CapsScorer{MinLetters: 12, MaxRatio: 0.7, Weight: 2},
+		&spam.RepeatScorer{MaxRun: 15, Weight: 2},
+		&spam.EmojiScorer{MaxEmoji: 15, Weight: 2},
+		&spam.MentionScorer{MaxMentions: s.MaxMentions, Weight: 1},
+	}
+
+	chain := spam.NewChain(s.HoldScore, s.RejectScore)
+	for _, scorer := range scorers {
+		if s.Rules[scorer.Name()] {
+			chain.Scorers = append(chain.Scorers, scorer)
+		}
+	}
+	return chain
+}
+
+// ScoreSpam 以目前的規則鏈評分訊息，與 FilterMessageForRoom 一起在發布訊息前執行
+func ScoreSpam(message spam.Message) (spam.Result, error) {
+	return SpamChain.Evaluate(Ctx, message)
+}
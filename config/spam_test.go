@@ -0,0 +1,36 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"example.com/m/config"
+	"example.com/m/spam"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadSpamSettings(t *testing.T) {
+	settings := config.LoadSpamSettings()
+	assert.Equal(t, 5.0, settings.HoldScore)
+	assert.Len(t, settings.Chain(spam.NewMemoryHistory()).Scorers, len(spam.AllRules))
+
+	t.Setenv("SPAM_RULES", "links,mentions")
+	t.Setenv("SPAM_REJECT_SCORE", "0")
+	t.Setenv("SPAM_DUPLICATE_WINDOW", "-1")
+	t.Setenv("SPAM_NEW_ACCOUNT_AGE", "3600")
+
+	settings = config.LoadSpamSettings()
+	assert.Equal(t, 0.0, settings.RejectScore)
+	assert.Equal(t, time.Minute, settings.DuplicateWindow) // 無效值時保留預設值
+	assert.Equal(t, time.Hour, settings.NewAccountAge)
+
+	chain := settings.Chain(spam.NewMemoryHistory())
+	var names []string
+	for _, scorer := range chain.Scorers {
+		names = append(names, scorer.Name())
+	}
+	assert.Equal(t, []string{spam.RuleLinks, spam.RuleMentions}, names)
+
+	t.Setenv("SPAM_RULES", "links,bogus")
+	assert.Len(t, config.LoadSpamSettings().Rules, len(spam.AllRules)) // 格式錯誤時保留預設值
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// UploadSettings 附件上傳的相關限制
+type UploadSettings struct {
+	Dir           string   // 本地存放目錄
+	MaxBytes      int64    // 單一檔案大小上限
+	AllowedTypes  []string // 允許的 MIME 類型，以 "/*" 結尾表示整個大類
+	ThumbnailSize int      // 縮圖最長邊的像素
+	MaxPixels     int      // 產生縮圖時允許的最大像素數（寬乘高），超過時不產生縮圖
+}
+
+// LoadUploadSettings 從環境變數讀取上傳設定
+func LoadUploadSettings() UploadSettings {
+	settings := UploadSettings{
+		Dir:           os.Getenv("UPLOAD_DIR"),
+		MaxBytes:      10 << 20, // 預設 10MB
+		AllowedTypes:  []string{"image/*", "application/pdf", "text/plain", "application/zip"},
+		ThumbnailSize: 256,
+		MaxPixels:     40_000_000,
+	}
+
+	if settings.Dir == "" {
+		settings.Dir = "./uploads"
+	}
+	if v, err := strconv.ParseInt(os.Getenv("UPLOAD_MAX_BYTES"), 10, 64); err == nil && v > 0 {
+		settings.MaxBytes = v
+	}
+	if v := os.Getenv("UPLOAD_ALLOWED_TYPES"); v != "" {
+		settings.AllowedTypes = strings.Split(v, ",")
+	}
+	if v, err := strconv.Atoi(os.Getenv("UPLOAD_THUMBNAIL_SIZE")); err == nil && v > 0 {
+		settings.ThumbnailSize = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("UPLOAD_MAX_PIXELS")); err == nil && v > 0 {
+		settings.MaxPixels = v
+	}
+
+	return settings
+}
+
+// IsAllowedType 檢查 MIME 類型是否在允許清單內
+func (s UploadSettings) IsAllowedType(mimeType string) bool {
+	for _, allowed := range s.AllowedTypes {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == mimeType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mimeType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package filter
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// Automaton 由 Matcher 編譯而成的唯讀 Aho-Corasick 狀態機。
+// 狀態依廣度優先順序編號，每個狀態的子節點連續存放並依字元排序，
+// 因此第 e 條邊必定指向狀態 e+1，只需要記錄邊的字元，不需要指標或 map。
+// 比對時以二分搜尋找子節點。五萬詞的詞庫約佔 4 MB，map 版本約 37 MB（見 BenchmarkMemory），且建立後可在多個 goroutine 間共用
+type Automaton struct {
+	patterns []string
+	lengths  []int32 // 每個敏感詞的 rune 數
+
+	edgeStart []int32 // 狀態 s 的邊為 labels[edgeStart[s]:edgeStart[s+1]]，長度為狀態數 + 1
+	labels    []rune  // 第 e 條邊的字元，指向狀態 e+1
+	fail      []int32 // 失敗指標
+	word      []int32 // 以此狀態結尾的敏感詞編號，沒有時為 -1
+	dict      []int32 // 沿失敗指標最近一個有敏感詞的狀態，沒有時為 -1
+}
+
+// Compile 將 Matcher 的字典樹編譯為 Automaton，Matcher 不需要先呼叫 Build
+func (m *Matcher) Compile() *Automaton {
+	a := &Automaton{edgeStart: []int32{0}}
+
+	// 敏感詞編號依插入順序，重複插入的詞只保留一個
+	index := make(map[string]int32, len(m.patterns))
+	for _, pattern := range m.patterns {
+		if _, ok := index[pattern]; !ok {
+			index[pattern] = int32(len(a.patterns))
+			a.patterns = append(a.patterns, pattern)
+			a.lengths = append(a.lengths, int32(utf8.RuneCountInString(pattern)))
+		}
+	}
+
+	// 廣度優先編號，子節點依字元排序
+	queue := []*node{m.root}
+	for i := 0; i < len(queue); i++ {
+		current := queue[i]
+
+		wordID := int32(-1)
+		if current.word != "" {
+			wordID = index[current.word]
+		}
+		a.word = append(a.word, wordID)
+
+		chars := make([]rune, 0, len(current.children))
+		for char := range current.children {
+			chars = append(chars, char)
+		}
+		sort.Slice(chars, func(i, j int) bool { return chars[i] < chars[j] })
+		for _, char := range chars {
+			a.labels = append(a.labels, char)
+			queue = append(queue, current.children[char])
+		}
+		a.edgeStart = append(a.edgeStart, int32(len(a.labels)))
+	}
+
+	a.buildFail()
+	return a
+}
+
+// buildFail 依廣度優先順序建立失敗指標與輸出鏈
+func (a *Automaton) buildFail() {
+	states := len(a.word)
+	a.fail = make([]int32, states)
+	a.dict = make([]int32, states)
+	a.dict[0] = -1
+
+	for s := int32(0); s < int32(states); s++ {
+		for e := a.edgeStart[s]; e < a.edgeStart[s+1]; e++ {
+			child, char := e+1, a.labels[e]
+			if s != 0 {
+				f := a.fail[s]
+				for {
+					if next := a.child(f, char); next >= 0 {
+						a.fail[child] = next
+						break
+					}
+					if f == 0 {
+						break
+					}
+					f = a.fail[f]
+				}
+			}
+
+			if f := a.fail[child]; a.word[f] >= 0 {
+				a.dict[child] = f
+			} else {
+				a.dict[child] = a.dict[f]
+			}
+		}
+	}
+}
+
+// child 返回狀態 s 經字元 char 到達的狀態，沒有時返回 -1
+func (a *Automaton) child(s int32, char rune) int32 {
+	low, high := a.edgeStart[s], a.edgeStart[s+1]
+	for low < high {
+		mid := int32(uint32(low+high) >> 1)
+		if a.labels[mid] < char {
+			low = mid + 1
+		} else {
+			high = mid
+		}
+	}
+	if low < a.edgeStart[s+1] && a.labels[low] == char {
+		return low + 1
+	}
+	return -1
+}
+
+// hasChildren 狀態 s 是否還有子節點
+func (a *Automaton) hasChildren(s int32) bool {
+	return a.edgeStart[s+1] > a.edgeStart[s]
+}
+
+// Patterns 返回敏感詞
+func (a *Automaton) Patterns() []string {
+	return a.patterns
+}
+
+// States 返回狀態數
+func (a *Automaton) States() int {
+	return len(a.word)
+}
+
+// Scan 依序回報文字中完整出現的敏感詞，fn 返回 false 時停止掃描。
+// 掃描本身不配置記憶體，適合只需要判斷或計數的呼叫端
+func (a *Automaton) Scan(content string, fn func(Match) bool) {
+	state := int32(0)
+	position := int32(0)
+	for _, char := range content {
+		position++
+		for {
+			if next := a.child(state, char); next >= 0 {
+				state = next
+				break
+			}
+			if state == 0 {
+				break
+			}
+			state = a.fail[state]
+		}
+
+		output := state
+		if a.word[output] < 0 {
+			output = a.dict[output]
+		}
+		for ; output > 0; output = a.dict[output] {
+			id := a.word[output]
+			if !fn(Match{Word: a.patterns[id], Span: Span{Start: int(position - a.lengths[id]), End: int(position)}}) {
+				return
+			}
+		}
+	}
+}
+
+// AppendMatches 將文字中完整出現的敏感詞附加到 dst 後返回，可重複使用同一個緩衝區以避免配置記憶體
+func (a *Automaton) AppendMatches(dst []Match, content string) []Match {
+	a.Scan(content, func(m Match) bool {
+		dst = append(dst, m)
+		return true
+	})
+	return dst
+}
+
+// Find 尋找文字中完整出現的敏感詞，返回每個命中的敏感詞與其位置
+func (a *Automaton) Find(content string) []Match {
+	return a.AppendMatches(nil, content)
+}
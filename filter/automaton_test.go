@@ -0,0 +1,274 @@
+package filter_test
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+
+	"example.com/m/filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutomatonMatchesMatcher(t *testing.T) {
+	words := []string{"he", "she", "his", "hers", "混蛋", "混", "蛋蛋", "a", "aa", "aaa", "😀x"}
+	matcher := newTestMatcher(words...)
+	automaton := newTestAutomaton(words...)
+
+	tests := []string{
+		"ushers",
+		"你这个混蛋蛋蛋",
+		"aaaa",
+		"😀😀x his",
+		"",
+		"nothing to see",
+	}
+	for _, content := range tests {
+		t.Run(content, func(t *testing.T) {
+			assert.Equal(t, matcher.Find(content), automaton.Find(content))
+		})
+	}
+
+	// 隨機字串與隨機詞庫比對結果也必須一致（Matcher 會重複輸出重複插入的詞，所以詞庫不含重複的詞）
+	random := rand.New(rand.NewSource(1))
+	alphabet := []rune("abc混蛋 ")
+	randomString := func(n int) string {
+		var builder strings.Builder
+		for i := 0; i < n; i++ {
+			builder.WriteRune(alphabet[random.Intn(len(alphabet))])
+		}
+		return builder.String()
+	}
+	for i := 0; i < 50; i++ {
+		var words []string
+		seen := make(map[string]bool)
+		for j := 0; j < 20; j++ {
+			if word := randomString(1 + random.Intn(4)); !seen[word] {
+				seen[word] = true
+				words = append(words, word)
+			}
+		}
+		content := randomString(60)
+		assert.Equal(t, newTestMatcher(words...).Find(content), newTestAutomaton(words...).Find(content), "words %q content %q", words, content)
+	}
+}
+
+func TestAutomatonScan(t *testing.T) {
+	automaton := newTestAutomaton("bad", "word")
+
+	var seen []string
+	automaton.Scan("bad word bad", func(m filter.Match) bool {
+		seen = append(seen, m.Word)
+		return len(seen) < 2
+	})
+	assert.Equal(t, []string{"bad", "word"}, seen) // 返回 false 後停止
+
+	assert.Equal(t, []string{"bad", "word"}, automaton.Patterns())
+	assert.Equal(t, 8, automaton.States())
+}
+
+func TestAutomatonZeroAllocation(t *testing.T) {
+	words, message := benchmarkDictionary(1000)
+	automaton := newTestAutomaton(words...)
+
+	count := 0
+	allocs := testing.AllocsPerRun(100, func() {
+		automaton.Scan(message, func(filter.Match) bool {
+			count++
+			return true
+		})
+	})
+	assert.Zero(t, allocs)
+
+	buf := make([]filter.Match, 0, 16)
+	allocs = testing.AllocsPerRun(100, func() {
+		buf = automaton.AppendMatches(buf[:0], "word1x and word2x")
+	})
+	assert.Zero(t, allocs)
+	assert.Len(t, buf, 2)
+}
+
+func TestAutomatonEncoding(t *testing.T) {
+	automaton := newTestAutomaton("he", "she", "hers", "混蛋", "😀")
+
+	var buf bytes.Buffer
+	n, err := automaton.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	decoded, err := filter.ReadAutomaton(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, automaton, decoded)
+	assert.Equal(t, automaton.Find("ushers 混蛋😀"), decoded.Find("ushers 混蛋😀"))
+
+	// 截斷或標頭錯誤的資料
+	_, err = filter.ReadAutomaton(bytes.NewReader(buf.Bytes()[:buf.Len()-2]))
+	assert.ErrorIs(t, err, filter.ErrInvalidEncoding)
+	_, err = filter.ReadAutomaton(strings.NewReader("nope"))
+	assert.ErrorIs(t, err, filter.ErrInvalidEncoding)
+
+	// 空的機器也能序列化
+	buf.Reset()
+	_, err = newTestAutomaton().WriteTo(&buf)
+	require.NoError(t, err)
+	empty, err := filter.ReadAutomaton(&buf)
+	require.NoError(t, err)
+	assert.Empty(t, empty.Find("anything"))
+}
+
+func TestDictionaryEncoding(t *testing.T) {
+	pinyin := func(o *filter.Options) { o.Pinyin = true }
+	dictionary := newDictionary([]filter.Entry{
+		{Word: "草泥马"},
+		{Word: "buynow", Category: filter.CategorySpam, Severity: 3},
+		{Word: "cunt", Action: filter.ActionReject},
+	}, []string{"Scunthorpe"}, pinyin)
+
+	var buf bytes.Buffer
+	_, err := dictionary.WriteTo(&buf)
+	require.NoError(t, err)
+
+	options := filter.DefaultOptions()
+	options.Pinyin = true
+	decoded, err := filter.ReadDictionary(bytes.NewReader(buf.Bytes()), options)
+	require.NoError(t, err)
+	assert.Equal(t, dictionary.Words(), decoded.Words())
+	assert.Equal(t, dictionary.AllowList(), decoded.AllowList())
+	for _, message := range []string{"caonima", "BUYNOW", "Scunthorpe", "you cunt", "clean"} {
+		assert.Equal(t, dictionary.Check(message, nil), decoded.Check(message, nil), message)
+	}
+
+	// 讀取時可以關閉拼音比對
+	decoded, err = filter.ReadDictionary(bytes.NewReader(buf.Bytes()), filter.DefaultOptions())
+	require.NoError(t, err)
+	assert.Equal(t, filter.ActionAllow, decoded.Check("caonima", nil).Action)
+
+	// 正規化步驟不同時詞庫必須重新建立
+	options.Normalize = filter.NormalizeNFKC
+	_, err = filter.ReadDictionary(bytes.NewReader(buf.Bytes()), options)
+	assert.ErrorIs(t, err, filter.ErrOptionsMismatch)
+
+	_, err = filter.ReadDictionary(bytes.NewReader(buf.Bytes()[:10]), filter.DefaultOptions())
+	assert.ErrorIs(t, err, filter.ErrInvalidEncoding)
+}
+
+// largeDictionary 產生混合中英文的大型詞庫，模擬數萬詞的正式詞庫
+func largeDictionary(n int) []string {
+	random := rand.New(rand.NewSource(42))
+	han := []rune("混蛋屎狗你他妈的草泥马操死废物傻逼滚贱人垃圾赌博诈骗")
+	words := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			words = append(words, fmt.Sprintf("spam%dword", i))
+			continue
+		}
+		var builder strings.Builder
+		for j := 0; j < 2+random.Intn(3); j++ {
+			builder.WriteRune(han[random.Intn(len(han))])
+		}
+		words = append(words, builder.String())
+	}
+	return words
+}
+
+// benchmarkText 一般聊天訊息中夾帶兩個敏感詞
+func benchmarkText(words []string) string {
+	return strings.Repeat("这是一条普通的聊天消息，没有什么特别的内容。this is a perfectly normal chat message. ", 4) + words[1] + " " + words[2]
+}
+
+func BenchmarkFind(b *testing.B) {
+	words := largeDictionary(50000)
+	message := benchmarkText(words)
+
+	b.Run("MapTrie", func(b *testing.B) {
+		matcher := newTestMatcher(words...)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			matcher.Find(message)
+		}
+	})
+	b.Run("Automaton", func(b *testing.B) {
+		automaton := newTestAutomaton(words...)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			automaton.Find(message)
+		}
+	})
+	b.Run("AutomatonAppend", func(b *testing.B) {
+		automaton := newTestAutomaton(words...)
+		var buf []filter.Match
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			buf = automaton.AppendMatches(buf[:0], message)
+		}
+	})
+	b.Run("AutomatonScan", func(b *testing.B) {
+		automaton := newTestAutomaton(words...)
+		count := 0
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			automaton.Scan(message, func(filter.Match) bool {
+				count++
+				return true
+			})
+		}
+	})
+}
+
+// retainedHeap 返回 build 建立的物件在 GC 後仍佔用的 heap 大小
+func retainedHeap(build func() interface{}) float64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	value := build()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(value)
+	return float64(after.HeapAlloc) - float64(before.HeapAlloc)
+}
+
+// BenchmarkMemory 以 heap-bytes 回報兩種表示法建立完成後的記憶體用量
+func BenchmarkMemory(b *testing.B) {
+	words := largeDictionary(50000)
+	b.Run("MapTrie", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.ReportMetric(retainedHeap(func() interface{} { return newTestMatcher(words...) }), "heap-bytes")
+		}
+	})
+	b.Run("Automaton", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.ReportMetric(retainedHeap(func() interface{} { return newTestAutomaton(words...) }), "heap-bytes")
+		}
+	})
+}
+
+// BenchmarkLoad 比較從詞庫建立與從序列化資料讀取所需的時間
+func BenchmarkLoad(b *testing.B) {
+	words := largeDictionary(50000)
+	b.Run("Build", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			newTestAutomaton(words...)
+		}
+	})
+	b.Run("Read", func(b *testing.B) {
+		var buf bytes.Buffer
+		if _, err := newTestAutomaton(words...).WriteTo(&buf); err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := filter.ReadAutomaton(bytes.NewReader(buf.Bytes())); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package filter
+
+import (
+	_ "embed"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 繁簡對照表與拼音表隨程式一起打包，不依賴外部服務
+var (
+	//go:embed data/t2s.txt
+	t2sData string
+
+	//go:embed data/pinyin.txt
+	pinyinData string
+
+	t2sTable    = parseT2STable(t2sData)
+	pinyinTable = parsePinyinTable(pinyinData)
+)
+
+// minPinyinWordLength 參與拼音比對的敏感詞最少字數，單字的同音字太多，容易誤判
+const minPinyinWordLength = 2
+
+func parseT2STable(data string) map[rune]rune {
+	table := make(map[rune]rune)
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		traditional, _ := utf8.DecodeRuneInString(fields[0])
+		simplified, _ := utf8.DecodeRuneInString(fields[1])
+		table[traditional] = simplified
+	}
+	return table
+}
+
+func parsePinyinTable(data string) map[rune]string {
+	table := make(map[rune]string)
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, char := range fields[1] {
+			table[char] = fields[0]
+		}
+	}
+	return table
+}
+
+// ToSimplified 將繁體字轉為簡體字，沒有對應時返回原字
+func ToSimplified(char rune) rune {
+	if simplified, ok := t2sTable[char]; ok {
+		return simplified
+	}
+	return char
+}
+
+// Pinyin 返回漢字不含聲調的拼音
+func Pinyin(char rune) (string, bool) {
+	pinyin, ok := pinyinTable[char]
+	return pinyin, ok
+}
+
+// pinyinText 將文字中的漢字轉為拼音，其他字元保持不變，並記錄每個字元來自原文的位置
+func pinyinText(text string) Normalized {
+	var builder strings.Builder
+	offsets := make([]int, 0, len(text))
+
+	index := 0
+	for _, char := range text {
+		if pinyin, ok := Pinyin(char); ok {
+			builder.WriteString(pinyin)
+			for range pinyin {
+				offsets = append(offsets, index)
+			}
+		} else {
+			builder.WriteRune(char)
+			offsets = append(offsets, index)
+		}
+		index++
+	}
+	return Normalized{Text: builder.String(), Offsets: offsets}
+}
+
+// wordPinyin 返回敏感詞的拼音，少於兩個漢字或有漢字沒有拼音時返回 false
+func wordPinyin(word string) (string, bool) {
+	var builder strings.Builder
+	han := 0
+	for _, char := range word {
+		if !unicode.Is(unicode.Han, char) {
+			builder.WriteRune(char)
+			continue
+		}
+		pinyin, ok := Pinyin(char)
+		if !ok {
+			return "", false
+		}
+		builder.WriteString(pinyin)
+		han++
+	}
+	return builder.String(), han >= minPinyinWordLength
+}
+
+// pinyinIndex 以敏感詞拼音建立的第二個 Aho-Corasick 機器，用於比對同音字與拼音
+type pinyinIndex struct {
+	matcher *Automaton
+	words   map[string]string // 拼音 → 敏感詞
+}
+
+// newPinyinIndex 以敏感詞的拼音建立索引，沒有可比對拼音的敏感詞時返回 nil
+func newPinyinIndex(patterns []string) *pinyinIndex {
+	matcher := NewMatcher()
+	words := make(map[string]string)
+	for _, word := range patterns {
+		pinyin, ok := wordPinyin(word)
+		if !ok {
+			continue
+		}
+		if _, exists := words[pinyin]; exists {
+			continue // 同音的敏感詞只保留第一個
+		}
+		words[pinyin] = word
+		matcher.Insert(pinyin)
+	}
+	if len(words) == 0 {
+		return nil
+	}
+
+	return &pinyinIndex{matcher: matcher.Compile(), words: words}
+}
+
+// match 以拼音比對訊息，返回對應回原敏感詞與正規化文字位置的命中。
+// 命中必須從漢字拼音的開頭到結尾，且至少涵蓋兩個字元，避免比對到跨字的拼音片段或單字
+func (p *pinyinIndex) match(text string) []Match {
+	if p == nil {
+		return nil
+	}
+
+	converted := pinyinText(text)
+	offsets := converted.Offsets
+	var matches []Match
+	for _, m := range p.matcher.Find(converted.Text) {
+		if m.Span.Start > 0 && offsets[m.Span.Start-1] == offsets[m.Span.Start] {
+			continue
+		}
+		if m.Span.End < len(offsets) && offsets[m.Span.End] == offsets[m.Span.End-1] {
+			continue
+		}
+		start, end := converted.OriginalSpan(m.Span.Start, m.Span.End)
+		if end-start < minPinyinWordLength {
+			continue
+		}
+		matches = append(matches, Match{Word: p.words[m.Word], Span: Span{Start: start, End: end}})
+	}
+	return matches
+}
@@ -0,0 +1,24 @@
+package filter_test
+
+import (
+	"testing"
+
+	"example.com/m/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToSimplified(t *testing.T) {
+	assert.Equal(t, '国', filter.ToSimplified('國'))
+	assert.Equal(t, '发', filter.ToSimplified('發'))
+	assert.Equal(t, '好', filter.ToSimplified('好'))
+	assert.Equal(t, "混蛋国家", filter.NormalizeText("混蛋國家", filter.NormalizeChinese).Text)
+}
+
+func TestPinyin(t *testing.T) {
+	pinyin, ok := filter.Pinyin('草')
+	assert.True(t, ok)
+	assert.Equal(t, "cao", pinyin)
+
+	_, ok = filter.Pinyin('a')
+	assert.False(t, ok)
+}
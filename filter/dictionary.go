@@ -0,0 +1,177 @@
+package filter
+
+import (
+	"strings"
+)
+
+// Options 過濾設定
+type Options struct {
+	CategoryActions map[string]string // 各分類的處理方式
+	Normalize       NormalizeSteps    // 比對前套用的正規化步驟
+	Split           SplitOptions      // 拆字檢測設定
+	Pinyin          bool              // 兩個字以上的中文敏感詞也比對同音字與直接輸入的拼音，例如「草泥马」、「caonima」
+	WordBoundary    bool              // 拉丁字母的敏感詞必須出現在單字邊界，"ass" 不會命中 "class"；中文等其他文字不受影響
+	Mask            MaskOptions       // 處理方式為 mask 時的遮蔽設定
+	HoldSeverity    int               // 命中的最高嚴重程度達到此值時至少暫緩發布（hold），0 表示不啟用
+}
+
+// DefaultOptions 預設設定：分類使用預設處理方式，啟用所有正規化與拆字檢測，不啟用拼音與單字邊界
+func DefaultOptions() Options {
+	return Options{
+		CategoryActions: DefaultCategoryActions(),
+		Normalize:       NormalizeAll,
+		Split:           DefaultSplitOptions(),
+		Mask:            DefaultMaskOptions(),
+	}
+}
+
+// Dictionary 建立完成的詞庫，包含敏感詞、分類資訊、允許詞與過濾設定。
+// 建立後不再修改，可在多個 goroutine 間共用；詞庫變更時建立新的 Dictionary 整體替換
+type Dictionary struct {
+	words   *Automaton
+	info    map[string]WordInfo // 每個敏感詞（正規化後）的分類資訊
+	pinyin  *pinyinIndex        // 未啟用拼音比對時為 nil
+	allow   *Automaton          // 允許詞，沒有允許詞時為 nil
+	options Options
+}
+
+// NewDictionary 以敏感詞與允許詞建立詞庫。敏感詞與允許詞會先去除前後空白並以 options.Normalize 正規化，
+// 處理後為空或重複的詞會被略過，重複時保留第一筆的分類資訊。
+// 落在允許詞之內的命中會被排除，例如允許 "scunthorpe" 後其中的敏感詞不再命中
+func NewDictionary(entries []Entry, allowTerms []string, options Options) *Dictionary {
+	d := &Dictionary{info: make(map[string]WordInfo), options: options}
+	words := NewMatcher()
+	for _, entry := range entries {
+		// 詞庫與訊息使用相同的正規化，讓全形、大小寫等變體都能命中
+		word := d.normalizeWord(entry.Word)
+		if _, exists := d.info[word]; word == "" || exists {
+			continue
+		}
+		words.Insert(word)
+		d.info[word] = entry.Info()
+	}
+	d.words = words.Compile()
+	if options.Pinyin {
+		d.pinyin = newPinyinIndex(d.words.Patterns())
+	}
+
+	allow := NewMatcher()
+	for _, term := range allowTerms {
+		if term = d.normalizeWord(term); term != "" {
+			allow.Insert(term)
+		}
+	}
+	if len(allow.Patterns()) > 0 {
+		d.allow = allow.Compile()
+	}
+	return d
+}
+
+// Options 返回詞庫使用的過濾設定
+func (d *Dictionary) Options() Options {
+	return d.options
+}
+
+// Words 返回詞庫中的敏感詞（正規化後）
+func (d *Dictionary) Words() []string {
+	return d.words.Patterns()
+}
+
+// AllowList 返回詞庫中的允許詞（正規化後）
+func (d *Dictionary) AllowList() []string {
+	if d.allow == nil {
+		return nil
+	}
+	return d.allow.Patterns()
+}
+
+// Info 返回敏感詞的分類資訊，未設定時使用預設分類
+func (d *Dictionary) Info(word string) WordInfo {
+	info, ok := d.info[word]
+	if !ok {
+		return WordInfo{Category: DefaultCategory, Severity: 1}
+	}
+	return info
+}
+
+// normalizeWord 以詞庫的正規化設定處理敏感詞，讓詞庫與訊息使用相同的形式比對
+func (d *Dictionary) normalizeWord(word string) string {
+	return NormalizeText(strings.TrimSpace(word), d.options.Normalize).Text
+}
+
+// match 在正規化後的文字中尋找敏感詞，包含拆字命中，啟用拼音比對時也包含同音字與拼音的命中
+func (d *Dictionary) match(content string) []Match {
+	matches := d.words.Find(content)
+	matches = append(matches, d.words.DetectSplit(content, d.options.Split)...)
+
+	seen := make(map[Match]bool, len(matches))
+	for _, m := range matches {
+		seen[m] = true
+	}
+	for _, m := range d.pinyin.match(content) {
+		if !seen[m] {
+			seen[m] = true
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// RoomDictionary 以詞庫的過濾設定建立房間專屬敏感詞的詞庫，policy 沒有房間詞時返回 nil。
+// 建立詞庫的成本與詞數成正比，呼叫端應快取結果，房間設定或全域詞庫變更時再重新建立
+func (d *Dictionary) RoomDictionary(policy *Policy) *Dictionary {
+	if policy == nil || len(policy.Words) == 0 {
+		return nil
+	}
+	return NewDictionary(policy.Words, nil, d.options)
+}
+
+// Check 過濾消息中的敏感詞（包含拆字），返回依敏感詞分類決定的處理結果；policy 為 nil 時只使用詞庫本身。
+// 消息先經過正規化（全形、大小寫、形近字、繁簡、leetspeak、不可見字元）再比對，
+// 命中位置透過對照表換回原文位置，遮蔽時只替換原文中對應的字元，其餘格式保持不變
+func (d *Dictionary) Check(message string, policy *Policy) Verdict {
+	return d.CheckWithRoom(message, policy, d.RoomDictionary(policy))
+}
+
+// CheckWithRoom 與 Check 相同，但使用已由 RoomDictionary 建立的房間詞庫，room 為 nil 時不比對房間詞
+func (d *Dictionary) CheckWithRoom(message string, policy *Policy, room *Dictionary) Verdict {
+	normalized := NormalizeText(message, d.options.Normalize)
+	matches := d.match(normalized.Text)
+
+	// 合併房間專屬敏感詞的結果，房間詞的分類資訊優先
+	lookup := d.Info
+	if room != nil {
+		matches = append(matches, room.match(normalized.Text)...)
+		lookup = func(word string) WordInfo {
+			if info, ok := room.info[word]; ok {
+				return info
+			}
+			return d.Info(word)
+		}
+	}
+
+	// 排除允許詞之內與不在單字邊界的命中
+	matches, suppressed := d.suppress(message, normalized, matches)
+
+	// 將命中位置換回原文位置
+	for i, m := range matches {
+		matches[i].Span = normalized.originalSpan(m.Span)
+	}
+	for i, hit := range suppressed {
+		suppressed[i].Span = normalized.originalSpan(hit.Span)
+	}
+
+	verdict := d.newVerdict(message, matches, lookup, policy)
+	verdict.Suppressed = append(suppressed, verdict.Suppressed...)
+
+	// 只遮蔽處理方式為 mask 的敏感詞
+	var spans []Span
+	for _, hit := range verdict.Hits {
+		if hit.Action == ActionMask {
+			spans = append(spans, hit.Spans...)
+		}
+	}
+	verdict.Content = MaskText(message, spans, d.options.Mask)
+
+	return verdict
+}
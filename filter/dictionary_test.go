@@ -0,0 +1,287 @@
+package filter_test
+
+import (
+	"fmt"
+	"testing"
+
+	"example.com/m/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+// newDictionary 以預設設定建立詞庫，configure 可調整設定
+func newDictionary(entries []filter.Entry, allowTerms []string, configure ...func(*filter.Options)) *filter.Dictionary {
+	options := filter.DefaultOptions()
+	for _, apply := range configure {
+		apply(&options)
+	}
+	return filter.NewDictionary(entries, allowTerms, options)
+}
+
+func words(words ...string) []filter.Entry {
+	entries := make([]filter.Entry, len(words))
+	for i, word := range words {
+		entries[i] = filter.Entry{Word: word}
+	}
+	return entries
+}
+
+func TestCheckVerdict(t *testing.T) {
+	dictionary := newDictionary([]filter.Entry{
+		{Word: "damn"},
+		{Word: "buynow", Category: filter.CategorySpam, Severity: 3},
+		{Word: "hello", Category: filter.CategoryProfanity, Action: filter.ActionFlag},
+	}, nil)
+
+	tests := []struct {
+		message     string
+		action      string
+		content     string
+		maxSeverity int
+	}{
+		{"clean message", filter.ActionAllow, "clean message", 0},
+		{"damn it", filter.ActionMask, "**** it", 1},
+		{"hello there", filter.ActionFlag, "hello there", 1},
+		{"damn buynow", filter.ActionReject, "**** buynow", 3}, // 多個命中時取最嚴格的處理方式
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			verdict := dictionary.Check(tt.message, nil)
+			assert.Equal(t, tt.action, verdict.Action)
+			assert.Equal(t, tt.content, verdict.Content)
+			assert.Equal(t, tt.maxSeverity, verdict.MaxSeverity)
+			assert.Equal(t, tt.message, verdict.Original)
+		})
+	}
+
+	verdict := dictionary.Check("damn buynow", nil)
+	assert.ElementsMatch(t, []string{filter.CategoryProfanity, filter.CategorySpam}, verdict.Categories())
+}
+
+func TestCheckCategoryActions(t *testing.T) {
+	dictionary := newDictionary([]filter.Entry{{Word: "vote", Category: filter.CategoryPolitical}}, nil, func(o *filter.Options) {
+		o.CategoryActions = filter.ParseCategoryActions("political=allow")
+	})
+
+	verdict := dictionary.Check("vote", nil)
+	assert.Equal(t, filter.ActionAllow, verdict.Action)
+	if assert.Len(t, verdict.Suppressed, 1) {
+		assert.Equal(t, filter.SuppressedCategory, verdict.Suppressed[0].Reason)
+	}
+}
+
+func TestCheckHoldSeverity(t *testing.T) {
+	dictionary := newDictionary([]filter.Entry{
+		{Word: "idiot", Severity: 1},
+		{Word: "scum", Severity: 3},
+		{Word: "traitor", Severity: 4, Action: filter.ActionReject},
+	}, nil, func(o *filter.Options) { o.HoldSeverity = 3 })
+
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"idiot", filter.ActionMask},
+		{"scum", filter.ActionHold},
+		{"idiot scum", filter.ActionHold},
+		{"traitor", filter.ActionReject}, // 更嚴格的處理方式不受影響
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			assert.Equal(t, tt.want, dictionary.Check(tt.message, nil).Action)
+		})
+	}
+}
+
+func TestCheckNormalized(t *testing.T) {
+	dictionary := newDictionary(words("shit", "ＦＵＣＫ"), nil)
+
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"oh ＳＨＩＴ!", "oh ****!"},
+		{"sh\u200bit", "*****"},
+		{"no 5h1t here", "no **** here"},
+		{"line one\nѕһіt", "line one\n****"},
+		{"s\u0336h\u0336i\u0336t\u0336!", "********!"}, // 刪除線的附加符號一併遮蔽
+		{"fuck", "****"},                               // 詞庫也經過正規化
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			assert.Equal(t, tt.want, dictionary.Check(tt.message, nil).Content)
+		})
+	}
+
+	raw := newDictionary(words("shit"), nil, func(o *filter.Options) { o.Normalize = 0 })
+	assert.Equal(t, "oh ＳＨＩＴ!", raw.Check("oh ＳＨＩＴ!", nil).Content)
+}
+
+func TestCheckSpans(t *testing.T) {
+	dictionary := newDictionary(words("混蛋", "shit"), nil)
+
+	verdict := dictionary.Check("你这个混蛋，\n真是 s.h.i.t！", nil)
+	assert.Equal(t, "你这个**，\n真是 *******！", verdict.Content)
+	if assert.Len(t, verdict.Hits, 2) {
+		assert.Equal(t, []filter.Span{{Start: 10, End: 17}}, verdict.Hits[0].Spans)
+		assert.True(t, verdict.Hits[0].Split)
+		assert.Equal(t, []filter.Span{{Start: 3, End: 5}}, verdict.Hits[1].Spans)
+		assert.False(t, verdict.Hits[1].Split)
+	}
+}
+
+func TestCheckMaskOptions(t *testing.T) {
+	dictionary := newDictionary(words("damn"), nil, func(o *filter.Options) {
+		o.Mask = filter.MaskOptions{Strategy: filter.MaskToken, Token: "[censored]"}
+	})
+	assert.Equal(t, "[censored] it", dictionary.Check("damn it", nil).Content)
+}
+
+func TestCheckTraditionalSimplified(t *testing.T) {
+	// 詞庫中的繁體詞也能命中簡體訊息，反之亦然
+	dictionary := newDictionary(words("賭博", "诈骗"), nil)
+
+	assert.Equal(t, "不要**", dictionary.Check("不要赌博", nil).Content)
+	assert.Equal(t, "小心**集團", dictionary.Check("小心詐騙集團", nil).Content)
+}
+
+func TestCheckPinyin(t *testing.T) {
+	pinyin := func(o *filter.Options) { o.Pinyin = true }
+	dictionary := newDictionary(words("草泥马", "蛋"), nil, pinyin)
+
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"你这个操你妈", "你这个***"},
+		{"caonima!", "*******!"},
+		{"CaoNiMa", "*******"},
+		{"鸡蛋", "鸡*"},
+		{"单", "单"}, // 單字敏感詞不比對同音字
+		{"macaonimade", "ma*******de"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			assert.Equal(t, tt.want, dictionary.Check(tt.message, nil).Content)
+		})
+	}
+
+	// 拼音必須對齊字的邊界，也不能由單一個字組成
+	dictionary = newDictionary(words("艾滋", "西安"), nil, pinyin)
+	assert.Equal(t, filter.ActionMask, dictionary.Check("爱资", nil).Action)
+	assert.Equal(t, filter.ActionAllow, dictionary.Check("开子", nil).Action)
+	assert.Equal(t, filter.ActionAllow, dictionary.Check("先", nil).Action)
+
+	// 未啟用時不比對拼音
+	assert.Equal(t, filter.ActionAllow, newDictionary(words("草泥马"), nil).Check("caonima", nil).Action)
+}
+
+func TestCheckAllowList(t *testing.T) {
+	dictionary := newDictionary(words("cunt", "ass"), []string{"Scunthorpe", "classic"})
+	assert.Equal(t, []string{"scunthorpe", "classic"}, dictionary.AllowList())
+
+	verdict := dictionary.Check("Scunthorpe is a classic town, you ass", nil)
+	assert.Equal(t, "Scunthorpe is a classic town, you ***", verdict.Content)
+	if assert.Len(t, verdict.Suppressed, 2) {
+		assert.Equal(t, filter.SuppressedHit{Word: "cunt", Span: filter.Span{Start: 1, End: 5}, Reason: filter.SuppressedAllowList, Detail: "scunthorpe"}, verdict.Suppressed[0])
+		assert.Equal(t, filter.SuppressedAllowList, verdict.Suppressed[1].Reason)
+	}
+
+	// 允許詞也經過正規化
+	assert.Equal(t, filter.ActionAllow, dictionary.Check("ＳＣＵＮＴＨＯＲＰＥ", nil).Action)
+}
+
+func TestCheckWordBoundary(t *testing.T) {
+	dictionary := newDictionary(words("ass", "蛋"), nil, func(o *filter.Options) { o.WordBoundary = true })
+
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"pass the class", "pass the class"},
+		{"you ass!", "you ***!"},
+		{"ASS", "***"},
+		{"鸡蛋", "鸡*"}, // 中文不受單字邊界影響
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			assert.Equal(t, tt.want, dictionary.Check(tt.message, nil).Content)
+		})
+	}
+
+	verdict := dictionary.Check("class", nil)
+	if assert.Len(t, verdict.Suppressed, 1) {
+		assert.Equal(t, filter.SuppressedWordBoundary, verdict.Suppressed[0].Reason)
+	}
+}
+
+func TestCheckPolicy(t *testing.T) {
+	dictionary := newDictionary([]filter.Entry{
+		{Word: "damn"},
+		{Word: "vote", Category: filter.CategoryPolitical},
+		{Word: "heck", Action: filter.ActionMask},
+	}, nil)
+
+	tests := []struct {
+		name    string
+		policy  *filter.Policy
+		message string
+		action  string
+		content string
+	}{
+		{"no policy", nil, "vote damn", filter.ActionHold, "vote ****"},
+		{"disabled category", &filter.Policy{Categories: []string{filter.CategoryProfanity}}, "vote damn", filter.ActionMask, "vote ****"},
+		{"action override", &filter.Policy{Actions: map[string]string{filter.CategoryPolitical: filter.ActionReject}}, "vote", filter.ActionReject, "vote"},
+		{"override beats word action", &filter.Policy{Actions: map[string]string{filter.CategoryProfanity: filter.ActionReject}}, "heck", filter.ActionReject, "heck"},
+		{"allow override", &filter.Policy{Actions: map[string]string{filter.CategoryProfanity: filter.ActionAllow}}, "damn", filter.ActionAllow, "damn"},
+		{"allow word", &filter.Policy{AllowWords: []string{"DAMN"}}, "damn", filter.ActionAllow, "damn"},
+		{"room word", &filter.Policy{Words: []filter.Entry{{Word: "jira", Category: filter.CategorySpam}}}, "see jira", filter.ActionReject, "see jira"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := dictionary.Check(tt.message, tt.policy)
+			assert.Equal(t, tt.action, verdict.Action)
+			assert.Equal(t, tt.content, verdict.Content)
+		})
+	}
+
+	policy := &filter.Policy{AllowWords: []string{"damn"}, Categories: []string{filter.CategoryProfanity}}
+	verdict := dictionary.Check("damn vote", policy)
+	assert.Equal(t, filter.ActionAllow, verdict.Action)
+	assert.ElementsMatch(t, []string{filter.SuppressedRoomAllow, filter.SuppressedRoomCategory},
+		[]string{verdict.Suppressed[0].Reason, verdict.Suppressed[1].Reason})
+}
+
+func TestPolicyValidate(t *testing.T) {
+	assert.NoError(t, (&filter.Policy{Actions: map[string]string{filter.CategorySpam: filter.ActionAllow}}).Validate())
+	assert.ErrorIs(t, (&filter.Policy{Categories: []string{"nope"}}).Validate(), filter.ErrInvalidEntry)
+	assert.ErrorIs(t, (&filter.Policy{Actions: map[string]string{filter.CategorySpam: "drop"}}).Validate(), filter.ErrInvalidEntry)
+	assert.ErrorIs(t, (&filter.Policy{Words: []filter.Entry{{Word: "a", Severity: -1}}}).Validate(), filter.ErrInvalidEntry)
+	assert.True(t, (*filter.Policy)(nil).IsEmpty())
+}
+
+func TestNewDictionarySkipsDuplicates(t *testing.T) {
+	dictionary := newDictionary([]filter.Entry{
+		{Word: "spam", Category: filter.CategorySpam},
+		{Word: "SPAM", Category: filter.CategoryPII},
+		{Word: " "},
+	}, nil)
+
+	assert.Equal(t, []string{"spam"}, dictionary.Words())
+	assert.Equal(t, filter.CategorySpam, dictionary.Info("spam").Category)
+	if verdict := dictionary.Check("spam", nil); assert.Len(t, verdict.Hits, 1) {
+		assert.Equal(t, 1, verdict.Hits[0].Count)
+	}
+}
+
+func BenchmarkCheck(b *testing.B) {
+	entries := make([]filter.Entry, 1000)
+	for i := range entries {
+		entries[i] = filter.Entry{Word: fmt.Sprintf("word%dx", i)}
+	}
+	_, message := benchmarkDictionary(0)
+	dictionary := newDictionary(entries, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dictionary.Check(message, nil)
+	}
+}
@@ -0,0 +1,310 @@
+package filter
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// 序列化格式的識別碼與版本
+const (
+	automatonMagic    = "ACAT"
+	dictionaryMagic   = "ACDT"
+	encodingVersion   = 1
+	maxEncodedEntries = 1 << 26 // 讀取時的數量上限，避免損毀的檔案造成過大的配置
+)
+
+var (
+	// ErrInvalidEncoding 序列化資料格式錯誤或版本不符
+	ErrInvalidEncoding = errors.New("invalid filter encoding")
+	// ErrOptionsMismatch 序列化詞庫使用的正規化步驟與目前設定不同，詞庫需要重新建立
+	ErrOptionsMismatch = errors.New("serialized dictionary was built with different normalize steps")
+)
+
+// encoder 以 varint 寫入序列化資料，發生錯誤後忽略之後的寫入
+type encoder struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (e *encoder) write(p []byte) {
+	if e.err != nil {
+		return
+	}
+	n, err := e.w.Write(p)
+	e.n += int64(n)
+	e.err = err
+}
+
+func (e *encoder) uvarint(value uint64) {
+	e.write(e.buf[:binary.PutUvarint(e.buf[:], value)])
+}
+
+func (e *encoder) varint(value int64) {
+	e.write(e.buf[:binary.PutVarint(e.buf[:], value)])
+}
+
+func (e *encoder) string(value string) {
+	e.uvarint(uint64(len(value)))
+	e.write([]byte(value))
+}
+
+func (e *encoder) bool(value bool) {
+	if value {
+		e.uvarint(1)
+	} else {
+		e.uvarint(0)
+	}
+}
+
+func (e *encoder) flush() (int64, error) {
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	return e.n, e.err
+}
+
+// decoder 讀取 encoder 寫入的資料，發生錯誤後之後的讀取都返回零值
+type decoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (d *decoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%w: %s", ErrInvalidEncoding, fmt.Sprintf(format, args...))
+	}
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	value, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		d.fail("%v", err)
+	}
+	return value
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	value, err := binary.ReadVarint(d.r)
+	if err != nil {
+		d.fail("%v", err)
+	}
+	return value
+}
+
+// count 讀取數量並檢查上限
+func (d *decoder) count() int {
+	value := d.uvarint()
+	if value > maxEncodedEntries {
+		d.fail("count %d out of range", value)
+		return 0
+	}
+	return int(value)
+}
+
+func (d *decoder) string() string {
+	length := d.count()
+	if d.err != nil {
+		return ""
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		d.fail("%v", err)
+		return ""
+	}
+	return string(buf)
+}
+
+func (d *decoder) bool() bool {
+	return d.uvarint() != 0
+}
+
+func (d *decoder) magic(expected string) {
+	buf := make([]byte, len(expected))
+	if _, err := io.ReadFull(d.r, buf); err != nil || string(buf) != expected {
+		d.fail("missing %s header", expected)
+		return
+	}
+	if version := d.uvarint(); d.err == nil && version != encodingVersion {
+		d.fail("unsupported version %d", version)
+	}
+}
+
+// WriteTo 將 Automaton 序列化寫入 w。只寫入敏感詞與字典樹，失敗指標在讀取時重建
+func (a *Automaton) WriteTo(w io.Writer) (int64, error) {
+	e := &encoder{w: bufio.NewWriter(w)}
+	e.write([]byte(automatonMagic))
+	e.uvarint(encodingVersion)
+	a.encode(e)
+	return e.flush()
+}
+
+func (a *Automaton) encode(e *encoder) {
+	e.uvarint(uint64(len(a.patterns)))
+	for _, pattern := range a.patterns {
+		e.string(pattern)
+	}
+
+	e.uvarint(uint64(len(a.word)))
+	for s := range a.word {
+		e.uvarint(uint64(a.edgeStart[s+1] - a.edgeStart[s]))
+		e.varint(int64(a.word[s]))
+	}
+	for _, label := range a.labels {
+		e.uvarint(uint64(label))
+	}
+}
+
+// ReadAutomaton 讀取 WriteTo 寫入的 Automaton
+func ReadAutomaton(r io.Reader) (*Automaton, error) {
+	d := &decoder{r: bufio.NewReader(r)}
+	d.magic(automatonMagic)
+	a := decodeAutomaton(d)
+	if d.err != nil {
+		return nil, d.err
+	}
+	return a, nil
+}
+
+func decodeAutomaton(d *decoder) *Automaton {
+	a := &Automaton{}
+	patterns := d.count()
+	for i := 0; i < patterns && d.err == nil; i++ {
+		pattern := d.string()
+		a.patterns = append(a.patterns, pattern)
+		a.lengths = append(a.lengths, int32(utf8.RuneCountInString(pattern)))
+	}
+
+	states := d.count()
+	if d.err == nil && states == 0 {
+		d.fail("automaton has no states")
+	}
+	a.edgeStart = make([]int32, 1, states+1)
+	a.word = make([]int32, 0, states)
+	for s := 0; s < states && d.err == nil; s++ {
+		edges := d.count()
+		word := d.varint()
+		if word < -1 || word >= int64(len(a.patterns)) {
+			d.fail("state %d refers to unknown pattern %d", s, word)
+		}
+		a.edgeStart = append(a.edgeStart, a.edgeStart[s]+int32(edges))
+		a.word = append(a.word, int32(word))
+	}
+	if d.err != nil {
+		return nil
+	}
+
+	// 每個根以外的狀態恰好由一條邊到達
+	if int(a.edgeStart[states]) != states-1 {
+		d.fail("automaton has %d edges for %d states", a.edgeStart[states], states)
+		return nil
+	}
+	a.labels = make([]rune, states-1)
+	for i := range a.labels {
+		a.labels[i] = rune(d.uvarint())
+	}
+	if d.err != nil {
+		return nil
+	}
+	for s := 0; s < states; s++ {
+		for e := a.edgeStart[s] + 1; e < a.edgeStart[s+1]; e++ {
+			if a.labels[e-1] >= a.labels[e] {
+				d.fail("edges of state %d are not sorted", s)
+				return nil
+			}
+		}
+	}
+
+	a.buildFail()
+	return a
+}
+
+// WriteTo 將詞庫序列化寫入 w，包含敏感詞的分類資訊、允許詞與拼音索引，讀取時不需要重新正規化與建立字典樹
+func (d *Dictionary) WriteTo(w io.Writer) (int64, error) {
+	e := &encoder{w: bufio.NewWriter(w)}
+	e.write([]byte(dictionaryMagic))
+	e.uvarint(encodingVersion)
+	e.uvarint(uint64(d.options.Normalize))
+
+	d.words.encode(e)
+	for _, word := range d.words.Patterns() {
+		info := d.info[word]
+		e.string(info.Category)
+		e.varint(int64(info.Severity))
+		e.string(info.Action)
+	}
+
+	e.bool(d.allow != nil)
+	if d.allow != nil {
+		d.allow.encode(e)
+	}
+
+	e.bool(d.pinyin != nil)
+	if d.pinyin != nil {
+		d.pinyin.matcher.encode(e)
+		for _, pinyin := range d.pinyin.matcher.Patterns() {
+			e.string(d.pinyin.words[pinyin])
+		}
+	}
+	return e.flush()
+}
+
+// ReadDictionary 讀取 Dictionary.WriteTo 寫入的詞庫並套用 options。
+// options.Normalize 必須與建立詞庫時相同，否則返回 ErrOptionsMismatch；
+// 啟用拼音比對但檔案中沒有拼音索引時會重新建立
+func ReadDictionary(r io.Reader, options Options) (*Dictionary, error) {
+	dec := &decoder{r: bufio.NewReader(r)}
+	dec.magic(dictionaryMagic)
+	normalize := NormalizeSteps(dec.uvarint())
+	if dec.err != nil {
+		return nil, dec.err
+	}
+	if normalize != options.Normalize {
+		return nil, ErrOptionsMismatch
+	}
+
+	d := &Dictionary{info: make(map[string]WordInfo), options: options}
+	d.words = decodeAutomaton(dec)
+	if dec.err != nil {
+		return nil, dec.err
+	}
+	for _, word := range d.words.Patterns() {
+		d.info[word] = WordInfo{Category: dec.string(), Severity: int(dec.varint()), Action: dec.string()}
+	}
+
+	if dec.bool() {
+		d.allow = decodeAutomaton(dec)
+	}
+
+	var pinyin *pinyinIndex
+	if dec.bool() {
+		pinyin = &pinyinIndex{matcher: decodeAutomaton(dec), words: make(map[string]string)}
+		if dec.err == nil {
+			for _, syllables := range pinyin.matcher.Patterns() {
+				pinyin.words[syllables] = dec.string()
+			}
+		}
+	}
+	if dec.err != nil {
+		return nil, dec.err
+	}
+
+	if options.Pinyin {
+		if pinyin == nil {
+			pinyin = newPinyinIndex(d.words.Patterns())
+		}
+		d.pinyin = pinyin
+	}
+	return d, nil
+}
@@ -0,0 +1,138 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 敏感詞的處理方式，依嚴格程度由低到高排列
+const (
+	ActionAllow  = "allow"  // 沒有命中任何敏感詞
+	ActionFlag   = "flag"   // 放行但標記，供事後檢視
+	ActionMask   = "mask"   // 以 * 遮蔽敏感詞後放行
+	ActionHold   = "hold"   // 暫不發布，等待版主審核
+	ActionReject = "reject" // 拒絕訊息並通知發送者
+)
+
+// 敏感詞分類
+const (
+	CategoryProfanity = "profanity"
+	CategoryPolitical = "political"
+	CategorySpam      = "spam"
+	CategoryPII       = "pii"
+
+	DefaultCategory = CategoryProfanity
+)
+
+var actionRank = map[string]int{
+	ActionAllow:  0,
+	ActionFlag:   1,
+	ActionMask:   2,
+	ActionHold:   3,
+	ActionReject: 4,
+}
+
+var knownCategories = map[string]bool{
+	CategoryProfanity: true,
+	CategoryPolitical: true,
+	CategorySpam:      true,
+	CategoryPII:       true,
+}
+
+// ErrInvalidEntry 敏感詞的分類或處理方式無效
+var ErrInvalidEntry = errors.New("invalid sensitive word entry")
+
+// DefaultCategoryActions 各分類預設的處理方式
+func DefaultCategoryActions() map[string]string {
+	return map[string]string{
+		CategoryProfanity: ActionMask,
+		CategoryPolitical: ActionHold,
+		CategorySpam:      ActionReject,
+		CategoryPII:       ActionMask,
+	}
+}
+
+// ParseCategoryActions 解析 "political=reject,spam=hold" 格式的分類處理方式，
+// 覆寫在預設值之上，無效的處理方式會被忽略
+func ParseCategoryActions(value string) map[string]string {
+	actions := DefaultCategoryActions()
+	for _, pair := range strings.Split(value, ",") {
+		category, action, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && IsValidAction(action) {
+			actions[strings.TrimSpace(category)] = action
+		}
+	}
+	return actions
+}
+
+// IsValidAction 檢查處理方式是否有效
+func IsValidAction(action string) bool {
+	_, ok := actionRank[action]
+	return ok
+}
+
+// IsValidCategory 檢查分類是否為已知分類
+func IsValidCategory(category string) bool {
+	return knownCategories[category]
+}
+
+// StricterAction 返回兩個處理方式中較嚴格者
+func StricterAction(a, b string) string {
+	if actionRank[b] > actionRank[a] {
+		return b
+	}
+	return a
+}
+
+// WordInfo 敏感詞的分類、嚴重程度與處理方式
+type WordInfo struct {
+	Category string `json:"category"`
+	Severity int    `json:"severity"`
+	Action   string `json:"action,omitempty"` // 空字串表示依分類決定
+}
+
+// ResolveAction 決定敏感詞實際的處理方式：詞本身有設定時優先，否則依分類，未知分類則遮蔽
+func (i WordInfo) ResolveAction(categoryActions map[string]string) string {
+	if i.Action != "" && IsValidAction(i.Action) {
+		return i.Action
+	}
+	if action, ok := categoryActions[i.Category]; ok {
+		return action
+	}
+	return ActionMask
+}
+
+// Entry 詞庫中的敏感詞，分類、嚴重程度與處理方式為選填
+type Entry struct {
+	Word     string `json:"word"`
+	Category string `json:"category,omitempty"`
+	Severity int    `json:"severity,omitempty"`
+	Action   string `json:"action,omitempty"`
+}
+
+// Info 將敏感詞轉為分類資訊，未指定的欄位使用預設值
+func (e Entry) Info() WordInfo {
+	info := WordInfo{Category: e.Category, Severity: e.Severity, Action: e.Action}
+	if info.Category == "" {
+		info.Category = DefaultCategory
+	}
+	if info.Severity <= 0 {
+		info.Severity = 1
+	}
+	return info
+}
+
+// Validate 檢查敏感詞的分類、嚴重程度與處理方式是否有效
+func (e Entry) Validate() error {
+	if e.Category != "" && !IsValidCategory(e.Category) {
+		return fmt.Errorf("%w: unknown category %q for word %q", ErrInvalidEntry, e.Category, e.Word)
+	}
+	if e.Severity < 0 {
+		return fmt.Errorf("%w: negative severity for word %q", ErrInvalidEntry, e.Word)
+	}
+	if e.Action != "" && (!IsValidAction(e.Action) || e.Action == ActionAllow) {
+		return fmt.Errorf("%w: invalid action %q for word %q", ErrInvalidEntry, e.Action, e.Word)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package filter_test
+
+import (
+	"testing"
+
+	"example.com/m/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntryValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry filter.Entry
+		valid bool
+	}{
+		{"category and action", filter.Entry{Word: "a", Category: filter.CategoryPII, Action: filter.ActionHold}, true},
+		{"defaults", filter.Entry{Word: "a"}, true},
+		{"unknown category", filter.Entry{Word: "a", Category: "unknown"}, false},
+		{"allow action", filter.Entry{Word: "a", Action: filter.ActionAllow}, false},
+		{"negative severity", filter.Entry{Word: "a", Severity: -1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.valid {
+				assert.NoError(t, tt.entry.Validate())
+			} else {
+				assert.ErrorIs(t, tt.entry.Validate(), filter.ErrInvalidEntry)
+			}
+		})
+	}
+}
+
+func TestEntryInfo(t *testing.T) {
+	assert.Equal(t, filter.WordInfo{Category: filter.DefaultCategory, Severity: 1}, filter.Entry{Word: "a"}.Info())
+	assert.Equal(t, filter.WordInfo{Category: filter.CategorySpam, Severity: 3, Action: filter.ActionFlag},
+		filter.Entry{Word: "a", Category: filter.CategorySpam, Severity: 3, Action: filter.ActionFlag}.Info())
+}
+
+func TestResolveAction(t *testing.T) {
+	actions := filter.DefaultCategoryActions()
+	assert.Equal(t, filter.ActionHold, filter.WordInfo{Category: filter.CategoryPolitical}.ResolveAction(actions))
+	assert.Equal(t, filter.ActionFlag, filter.WordInfo{Category: filter.CategorySpam, Action: filter.ActionFlag}.ResolveAction(actions))
+	assert.Equal(t, filter.ActionMask, filter.WordInfo{Category: "unknown"}.ResolveAction(actions))
+}
+
+func TestStricterAction(t *testing.T) {
+	assert.Equal(t, filter.ActionHold, filter.StricterAction(filter.ActionMask, filter.ActionHold))
+	assert.Equal(t, filter.ActionReject, filter.StricterAction(filter.ActionReject, filter.ActionFlag))
+}
+
+func TestParseCategoryActions(t *testing.T) {
+	actions := filter.ParseCategoryActions(" political=reject, spam=drop,pii=flag")
+	assert.Equal(t, filter.ActionReject, actions[filter.CategoryPolitical])
+	assert.Equal(t, filter.ActionReject, actions[filter.CategorySpam]) // 無效的處理方式保留預設值
+	assert.Equal(t, filter.ActionFlag, actions[filter.CategoryPII])
+	assert.Equal(t, filter.ActionMask, actions[filter.CategoryProfanity])
+	assert.Equal(t, filter.DefaultCategoryActions(), filter.ParseCategoryActions(""))
+}
@@ -0,0 +1,213 @@
+// Package importer 將 TXT、JSON、CSV 與 XLSX 格式的詞庫檔案解析為 filter.Entry
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"example.com/m/filter"
+	"github.com/360EntSecGroup-Skylar/excelize"
+)
+
+// Importer 將特定格式的詞庫內容解析為敏感詞
+type Importer interface {
+	Import(r io.Reader) ([]filter.Entry, error)
+}
+
+// Options 表格類格式（CSV、XLSX）的讀取設定
+type Options struct {
+	Sheet     string // XLSX 工作表名稱，空字串表示第一個工作表
+	Column    int    // 詞所在的欄位（從 0 開始），-1 表示每個儲存格都是一個詞
+	HasHeader bool   // 第一行是否為標題列
+}
+
+// DefaultOptions 與舊版 Excel 讀取行為相同：第一行為標題，每個儲存格都是一個詞
+func DefaultOptions() Options {
+	return Options{Column: -1, HasHeader: true}
+}
+
+// New 依格式名稱（csv、txt、json、xlsx）建立匯入器
+func New(format string, options Options) (Importer, error) {
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case "csv":
+		return CSV{Options: options}, nil
+	case "txt", "text":
+		return TXT{}, nil
+	case "json":
+		return JSON{}, nil
+	case "xlsx":
+		return XLSX{Options: options}, nil
+	default:
+		return nil, fmt.Errorf("unsupported dictionary format %q", format)
+	}
+}
+
+// ForFile 依副檔名選擇匯入器
+func ForFile(filePath string, options Options) (Importer, error) {
+	return New(filepath.Ext(filePath), options)
+}
+
+// TXT 每行一個詞，忽略空行與 # 開頭的註解
+type TXT struct{}
+
+func (TXT) Import(r io.Reader) ([]filter.Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []filter.Entry
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, filter.Entry{Word: line})
+	}
+	return entries, nil
+}
+
+// JSON 接受字串陣列 ["a", "b"] 或物件陣列 [{"word": "a", "category": "spam"}]
+type JSON struct{}
+
+func (JSON) Import(r io.Reader) ([]filter.Entry, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	entries := make([]filter.Entry, 0, len(raw))
+	for _, item := range raw {
+		var word string
+		if err := json.Unmarshal(item, &word); err == nil {
+			entries = append(entries, filter.Entry{Word: word})
+			continue
+		}
+
+		var entry filter.Entry
+		if err := json.Unmarshal(item, &entry); err != nil {
+			return nil, fmt.Errorf("invalid dictionary entry %s: %w", item, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// CSV 讀取 CSV，標題列含 word 欄位時依欄位名稱讀取分類資訊
+type CSV struct {
+	Options Options
+}
+
+func (i CSV) Import(r io.Reader) ([]filter.Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return entriesFromRows(rows, i.Options)
+}
+
+// XLSX 讀取任意工作表，欄位規則與 CSV 相同
+type XLSX struct {
+	Options Options
+}
+
+func (i XLSX) Import(r io.Reader) ([]filter.Entry, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := i.Options.Sheet
+	if sheet == "" {
+		sheet = firstSheet(f)
+	}
+	if f.GetSheetIndex(sheet) == 0 {
+		return nil, fmt.Errorf("sheet %q not found", sheet)
+	}
+
+	return entriesFromRows(f.GetRows(sheet), i.Options)
+}
+
+// firstSheet 返回活頁簿中索引最小的工作表名稱
+func firstSheet(f *excelize.File) string {
+	first, name := 0, ""
+	for index, sheetName := range f.GetSheetMap() {
+		if first == 0 || index < first {
+			first, name = index, sheetName
+		}
+	}
+	return name
+}
+
+// entriesFromRows 將表格資料轉為敏感詞：
+// 標題列包含 word 欄位時依欄位名稱讀取 word/category/severity/action，
+// 否則依 Column 讀取單一欄位，Column 為 -1 時每個儲存格都是一個詞
+func entriesFromRows(rows [][]string, options Options) ([]filter.Entry, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	columns := map[string]int{}
+	if options.HasHeader {
+		for index, name := range rows[0] {
+			columns[strings.ToLower(strings.TrimSpace(name))] = index
+		}
+		rows = rows[1:]
+	}
+
+	cell := func(row []string, index int) string {
+		if index < 0 || index >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[index])
+	}
+
+	var entries []filter.Entry
+	if wordColumn, ok := columns["word"]; ok {
+		for line, row := range rows {
+			entry := filter.Entry{Word: cell(row, wordColumn)}
+			if entry.Word == "" {
+				continue
+			}
+			if index, ok := columns["category"]; ok {
+				entry.Category = cell(row, index)
+			}
+			if index, ok := columns["action"]; ok {
+				entry.Action = cell(row, index)
+			}
+			if index, ok := columns["severity"]; ok && cell(row, index) != "" {
+				severity, err := strconv.Atoi(cell(row, index))
+				if err != nil {
+					return nil, fmt.Errorf("invalid severity on row %d: %w", line+2, err)
+				}
+				entry.Severity = severity
+			}
+			entries = append(entries, entry)
+		}
+		return entries, nil
+	}
+
+	for _, row := range rows {
+		if options.Column >= 0 {
+			if word := cell(row, options.Column); word != "" {
+				entries = append(entries, filter.Entry{Word: word})
+			}
+			continue
+		}
+		for index := range row {
+			if word := cell(row, index); word != "" {
+				entries = append(entries, filter.Entry{Word: word})
+			}
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,95 @@
+package importer_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"example.com/m/filter"
+	"example.com/m/filter/importer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTXTImporter(t *testing.T) {
+	entries, err := importer.TXT{}.Import(strings.NewReader("# 註解\nbad\n\n  混蛋 \r\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []filter.Entry{{Word: "bad"}, {Word: "混蛋"}}, entries)
+}
+
+func TestJSONImporter(t *testing.T) {
+	entries, err := importer.JSON{}.Import(strings.NewReader(`["bad", {"word": "spam", "category": "spam", "severity": 2, "action": "reject"}]`))
+	assert.NoError(t, err)
+	assert.Equal(t, []filter.Entry{
+		{Word: "bad"},
+		{Word: "spam", Category: "spam", Severity: 2, Action: "reject"},
+	}, entries)
+
+	_, err = importer.JSON{}.Import(strings.NewReader(`[1]`))
+	assert.Error(t, err)
+}
+
+func TestCSVImporter(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		options importer.Options
+		want    []filter.Entry
+	}{
+		{
+			name:    "metadata columns",
+			input:   "Word,Category,Severity,Action\nbad,profanity,2,mask\nspam,spam,,\n",
+			options: importer.DefaultOptions(),
+			want: []filter.Entry{
+				{Word: "bad", Category: "profanity", Severity: 2, Action: "mask"},
+				{Word: "spam", Category: "spam"},
+			},
+		},
+		{
+			name:    "every cell",
+			input:   "中文,英文\n混蛋,jackass\n屎,\n",
+			options: importer.DefaultOptions(),
+			want:    []filter.Entry{{Word: "混蛋"}, {Word: "jackass"}, {Word: "屎"}},
+		},
+		{
+			name:    "single column without header",
+			input:   "混蛋,jackass\n屎,bitch\n",
+			options: importer.Options{Column: 1},
+			want:    []filter.Entry{{Word: "jackass"}, {Word: "bitch"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := importer.CSV{Options: tt.options}.Import(strings.NewReader(tt.input))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, entries)
+		})
+	}
+
+	_, err := importer.CSV{Options: importer.DefaultOptions()}.Import(strings.NewReader("word,severity\nbad,high\n"))
+	assert.Error(t, err)
+}
+
+func TestXLSXImporter(t *testing.T) {
+	f, err := os.Open("../../combined_sensitive_words.xlsx")
+	if err != nil {
+		t.Skip("dictionary file not available")
+	}
+	defer f.Close()
+
+	entries, err := importer.XLSX{Options: importer.DefaultOptions()}.Import(f)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, entries)
+	assert.Contains(t, entries, filter.Entry{Word: "jackass"})
+	assert.NotContains(t, entries, filter.Entry{Word: "中文"}) // 標題列不是敏感詞
+}
+
+func TestNew(t *testing.T) {
+	for _, format := range []string{"csv", ".txt", "json", "XLSX"} {
+		_, err := importer.New(format, importer.DefaultOptions())
+		assert.NoError(t, err, format)
+	}
+
+	_, err := importer.New("doc", importer.DefaultOptions())
+	assert.Error(t, err)
+}
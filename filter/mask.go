@@ -0,0 +1,83 @@
+package filter
+
+import (
+	"sort"
+)
+
+// 遮蔽方式
+const (
+	MaskFull      = "full"       // 每個字元都替換為遮蔽字元
+	MaskKeepFirst = "keep-first" // 保留第一個字元，其餘替換為遮蔽字元
+	MaskToken     = "token"      // 整段替換為固定字串
+)
+
+// MaskOptions 遮蔽設定
+type MaskOptions struct {
+	Strategy string
+	Char     rune   // full 與 keep-first 使用的遮蔽字元
+	Token    string // token 使用的替換字串
+}
+
+// DefaultMaskOptions 預設以 * 替換每個字元
+func DefaultMaskOptions() MaskOptions {
+	return MaskOptions{Strategy: MaskFull, Char: '*', Token: "***"}
+}
+
+// IsValidMaskStrategy 檢查遮蔽方式是否有效
+func IsValidMaskStrategy(strategy string) bool {
+	return strategy == MaskFull || strategy == MaskKeepFirst || strategy == MaskToken
+}
+
+// MaskText 依遮蔽設定替換原文中指定範圍的字元，重疊或相鄰的範圍會合併後再遮蔽，範圍以外的內容保持不變
+func MaskText(text string, spans []Span, options MaskOptions) string {
+	if len(spans) == 0 {
+		return text
+	}
+
+	runes := []rune(text)
+	merged := mergeSpans(spans, len(runes))
+
+	result := make([]rune, 0, len(runes))
+	position := 0
+	for _, span := range merged {
+		result = append(result, runes[position:span.Start]...)
+		switch options.Strategy {
+		case MaskToken:
+			result = append(result, []rune(options.Token)...)
+		case MaskKeepFirst:
+			result = append(result, runes[span.Start])
+			for i := span.Start + 1; i < span.End; i++ {
+				result = append(result, options.Char)
+			}
+		default:
+			for i := span.Start; i < span.End; i++ {
+				result = append(result, options.Char)
+			}
+		}
+		position = span.End
+	}
+	result = append(result, runes[position:]...)
+	return string(result)
+}
+
+// mergeSpans 排序並合併重疊或相鄰的範圍，超出文字長度的部分會被截斷
+func mergeSpans(spans []Span, length int) []Span {
+	sorted := make([]Span, 0, len(spans))
+	for _, span := range spans {
+		span.Start, span.End = max(span.Start, 0), min(span.End, length)
+		if span.Start < span.End {
+			sorted = append(sorted, span)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	var merged []Span
+	for _, span := range sorted {
+		if last := len(merged) - 1; last >= 0 && span.Start <= merged[last].End {
+			merged[last].End = max(merged[last].End, span.End)
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
@@ -0,0 +1,36 @@
+package filter_test
+
+import (
+	"testing"
+
+	"example.com/m/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskText(t *testing.T) {
+	full := filter.MaskOptions{Strategy: filter.MaskFull, Char: '*'}
+	keepFirst := filter.MaskOptions{Strategy: filter.MaskKeepFirst, Char: '#'}
+	token := filter.MaskOptions{Strategy: filter.MaskToken, Token: "[censored]"}
+
+	tests := []struct {
+		name    string
+		text    string
+		spans   []filter.Span
+		options filter.MaskOptions
+		want    string
+	}{
+		{"full", "you damn fool", []filter.Span{{Start: 4, End: 8}}, full, "you **** fool"},
+		{"chinese counts runes", "你是混蛋！", []filter.Span{{Start: 2, End: 4}}, full, "你是**！"},
+		{"keep first", "damn it", []filter.Span{{Start: 0, End: 4}}, keepFirst, "d### it"},
+		{"token", "damn it", []filter.Span{{Start: 0, End: 4}}, token, "[censored] it"},
+		{"overlapping spans merge", "abcdef", []filter.Span{{Start: 3, End: 5}, {Start: 1, End: 4}}, token, "a[censored]f"},
+		{"formatting kept", "line 1\n  damn\t😀", []filter.Span{{Start: 9, End: 13}}, full, "line 1\n  ****\t😀"},
+		{"out of range", "abc", []filter.Span{{Start: 2, End: 10}}, full, "ab*"},
+		{"no spans", "abc", nil, full, "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, filter.MaskText(tt.text, tt.spans, tt.options))
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Package filter 敏感詞過濾：Aho-Corasick 比對、拆字檢測、正規化、繁簡與拼音比對、允許詞與遮蔽。
+// 套件不依賴資料庫或全域設定，詞庫與設定都透過 Dictionary 傳入，可在伺服器與命令列工具間共用
+package filter
+
+import (
+	"unicode/utf8"
+)
+
+// Matcher 以 map 表示的 Aho-Corasick 狀態機，可逐一插入敏感詞，建立完成後以 Compile 轉為精簡的 Automaton
+type Matcher struct {
+	root     *node
+	patterns []string
+}
+
+// node 表示 Aho-Corasick 中的一個節點
+type node struct {
+	children map[rune]*node
+	fail     *node
+	output   []string
+	word     string // 以此節點結尾的敏感詞
+}
+
+// NewMatcher 新建 Aho-Corasick 狀態機
+func NewMatcher() *Matcher {
+	return &Matcher{root: &node{children: make(map[rune]*node)}}
+}
+
+// Insert 插入敏感詞，插入完畢後需呼叫 Build
+func (m *Matcher) Insert(pattern string) {
+	current := m.root
+	for _, char := range pattern {
+		if _, ok := current.children[char]; !ok {
+			current.children[char] = &node{children: make(map[rune]*node)}
+		}
+		current = current.children[char]
+	}
+	current.output = append(current.output, pattern)
+	current.word = pattern
+	m.patterns = append(m.patterns, pattern)
+}
+
+// Patterns 返回已插入的敏感詞
+func (m *Matcher) Patterns() []string {
+	return m.patterns
+}
+
+// Build 建立失敗指標
+func (m *Matcher) Build() {
+	queue := []*node{m.root}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for char, child := range current.children {
+			// 設置失敗指標
+			failNode := current.fail
+			for failNode != nil {
+				if next, ok := failNode.children[char]; ok {
+					child.fail = next
+					break
+				}
+				failNode = failNode.fail
+			}
+			if child.fail == nil {
+				child.fail = m.root
+			}
+			child.output = append(child.output, child.fail.output...)
+			queue = append(queue, child)
+		}
+	}
+}
+
+// Span 文字中的範圍，以 rune 索引表示，不含 End
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Match 命中的敏感詞與其位置
+type Match struct {
+	Word  string
+	Span  Span
+	Split bool // 是否為拆字命中
+}
+
+// Find 尋找文字中完整出現的敏感詞，返回每個命中的敏感詞與其位置
+func (m *Matcher) Find(content string) []Match {
+	current := m.root
+	var matches []Match
+
+	position := 0
+	for _, char := range content {
+		position++
+		for current != m.root && current.children[char] == nil {
+			current = current.fail
+		}
+		current = current.children[char]
+
+		if current == nil {
+			current = m.root
+		}
+
+		for _, pattern := range current.output {
+			matches = append(matches, Match{Word: pattern, Span: Span{Start: position - utf8.RuneCountInString(pattern), End: position}})
+		}
+	}
+
+	return matches
+}
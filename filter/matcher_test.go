@@ -0,0 +1,51 @@
+package filter_test
+
+import (
+	"testing"
+
+	"example.com/m/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatcherFind(t *testing.T) {
+	matcher := newTestMatcher("badword", "he", "she", "his", "hers", "混蛋")
+
+	tests := []struct {
+		name    string
+		content string
+		want    []filter.Match
+	}{
+		{"single", "It is a badword in a sentence.", []filter.Match{{Word: "badword", Span: filter.Span{Start: 8, End: 15}}}},
+		{"overlapping", "ushers", []filter.Match{
+			{Word: "she", Span: filter.Span{Start: 1, End: 4}},
+			{Word: "he", Span: filter.Span{Start: 2, End: 4}},
+			{Word: "hers", Span: filter.Span{Start: 2, End: 6}},
+		}},
+		{"runes", "你这个混蛋", []filter.Match{{Word: "混蛋", Span: filter.Span{Start: 3, End: 5}}}},
+		{"repeated", "混蛋混蛋", []filter.Match{
+			{Word: "混蛋", Span: filter.Span{Start: 0, End: 2}},
+			{Word: "混蛋", Span: filter.Span{Start: 2, End: 4}},
+		}},
+		{"no match", "clean message", nil},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matcher.Find(tt.content))
+		})
+	}
+}
+
+func TestMatcherPatterns(t *testing.T) {
+	assert.Equal(t, []string{"test", "sample"}, newTestMatcher("test", "sample").Patterns())
+	assert.Empty(t, newTestMatcher().Find("anything"))
+}
+
+func BenchmarkMatcherFind(b *testing.B) {
+	words, message := benchmarkDictionary(1000)
+	matcher := newTestMatcher(words...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		matcher.Find(message)
+	}
+}
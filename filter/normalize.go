@@ -0,0 +1,171 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeSteps 過濾前對訊息套用的正規化步驟
+type NormalizeSteps uint8
+
+const (
+	NormalizeNFKC      NormalizeSteps = 1 << iota // 相容分解後再組合，全形與相容字元轉為一般字元
+	NormalizeCaseFold                             // 大小寫摺疊
+	NormalizeHomoglyph                            // 形近字元（西里爾、希臘字母等）轉為拉丁字母
+	NormalizeLeetspeak                            // leetspeak 替換，例如 5h1t → shit
+	NormalizeInvisible                            // 去除零寬字元等不可見字元
+	NormalizeChinese                              // 繁體字轉為簡體字
+	NormalizeMarks                                // 去除組合附加符號（重音、刪除線等），例如 b̶a̶d̶ → bad
+
+	NormalizeAll = NormalizeNFKC | NormalizeCaseFold | NormalizeHomoglyph | NormalizeLeetspeak | NormalizeInvisible | NormalizeChinese | NormalizeMarks
+)
+
+var normalizeNames = map[string]NormalizeSteps{
+	"nfkc":      NormalizeNFKC,
+	"casefold":  NormalizeCaseFold,
+	"homoglyph": NormalizeHomoglyph,
+	"leetspeak": NormalizeLeetspeak,
+	"invisible": NormalizeInvisible,
+	"chinese":   NormalizeChinese,
+	"marks":     NormalizeMarks,
+}
+
+// ParseNormalizeSteps 解析以逗號分隔的正規化步驟，例如 "nfkc,casefold"，未知名稱會被忽略
+func ParseNormalizeSteps(value string) NormalizeSteps {
+	var steps NormalizeSteps
+	for _, name := range strings.Split(value, ",") {
+		steps |= normalizeNames[strings.ToLower(strings.TrimSpace(name))]
+	}
+	return steps
+}
+
+// 形近字元對照表（已大小寫摺疊後的字元）
+var homoglyphs = map[rune]rune{
+	// 西里爾字母
+	'а': 'a', 'в': 'b', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'ё': 'e', 'һ': 'h', 'н': 'h',
+	'і': 'i', 'ї': 'i', 'ј': 'j', 'к': 'k', 'ӏ': 'l', 'м': 'm', 'о': 'o', 'р': 'p',
+	'ԛ': 'q', 'ѕ': 's', 'т': 't', 'у': 'y', 'ү': 'y', 'х': 'x', 'ԝ': 'w', 'ь': 'b',
+	// 希臘字母
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w',
+	// 其他拉丁變體
+	'ı': 'i', 'ɡ': 'g', 'ſ': 's',
+}
+
+// leetspeak 替換對照表
+var leetspeak = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b',
+	'@': 'a', '$': 's', '!': 'i', '+': 't',
+}
+
+// stripMarks 以 NFD 分解後去除組合附加符號（unicode.Mn），再組合回 NFC，韓文等以組合字元表示的文字不受影響
+func stripMarks(segment string) string {
+	decomposed := norm.NFD.String(segment)
+	stripped := strings.Map(func(char rune) rune {
+		if unicode.Is(unicode.Mn, char) {
+			return -1
+		}
+		return char
+	}, decomposed)
+	if len(stripped) == len(decomposed) {
+		return segment
+	}
+	return norm.NFC.String(stripped)
+}
+
+// Normalized 正規化後的文字，以及每個字元對應到原文的位置
+type Normalized struct {
+	Text    string
+	Offsets []int // Offsets[i] 為正規化後第 i 個字元在原文中的 rune 索引
+	Ends    []int // Ends[i] 為第 i 個字元在原文中的結束索引，包含同一片段中被移除的附加符號；nil 時為 Offsets[i]+1
+}
+
+// OriginalSpan 將正規化文字中的字元範圍 [start, end) 轉為原文中的 rune 範圍
+func (n Normalized) OriginalSpan(start, end int) (int, int) {
+	if start >= end || end > len(n.Offsets) {
+		return 0, 0
+	}
+	if n.Ends != nil {
+		return n.Offsets[start], n.Ends[end-1]
+	}
+	return n.Offsets[start], n.Offsets[end-1] + 1
+}
+
+func (n Normalized) originalSpan(span Span) Span {
+	start, end := n.OriginalSpan(span.Start, span.End)
+	return Span{Start: start, End: end}
+}
+
+// NormalizeText 依序套用 NFKC、去除附加符號、大小寫摺疊、形近字元、繁簡轉換、leetspeak 與不可見字元處理，
+// 並記錄每個輸出字元來自原文的哪個字元，讓遮蔽能對應回原文
+func NormalizeText(text string, steps NormalizeSteps) Normalized {
+	var builder strings.Builder
+	builder.Grow(len(text))
+	offsets := make([]int, 0, len(text))
+	ends := make([]int, 0, len(text))
+	fold := cases.Fold()
+
+	emit := func(segment string, start, runes int) {
+		if steps&NormalizeMarks != 0 {
+			segment = stripMarks(segment)
+		}
+		if steps&NormalizeCaseFold != 0 {
+			segment = fold.String(segment)
+		}
+		emitted := len(offsets)
+		i := -1
+		for _, char := range segment {
+			i++
+			if steps&NormalizeInvisible != 0 && isZeroWidth(char) {
+				continue
+			}
+			if steps&NormalizeHomoglyph != 0 {
+				if mapped, ok := homoglyphs[char]; ok {
+					char = mapped
+				}
+			}
+			if steps&NormalizeChinese != 0 {
+				char = ToSimplified(char)
+			}
+			if steps&NormalizeLeetspeak != 0 {
+				if mapped, ok := leetspeak[char]; ok {
+					char = mapped
+				}
+			}
+			builder.WriteRune(char)
+			// 一個原文片段展開為多個字元時，依序對應到片段中的字元
+			offset := start + min(i, max(runes, 1)-1)
+			offsets = append(offsets, offset)
+			ends = append(ends, offset+1)
+		}
+		// 片段的最後一個字元涵蓋到片段結尾，遮蔽時一併替換被移除的附加符號
+		if last := len(ends) - 1; last >= emitted {
+			ends[last] = max(ends[last], start+runes)
+		}
+	}
+
+	runeIndex := 0
+	if steps&NormalizeNFKC != 0 {
+		var iter norm.Iter
+		iter.InitString(norm.NFKC, text)
+		for !iter.Done() {
+			startByte := iter.Pos()
+			segment := string(iter.Next())
+			// 展開的相容字元可能分成多段輸出，前面的片段不消耗原文，對應到下一個原文字元
+			runes := utf8.RuneCountInString(text[startByte:iter.Pos()])
+			emit(segment, runeIndex, runes)
+			runeIndex += runes
+		}
+	} else {
+		for _, char := range text {
+			emit(string(char), runeIndex, 1)
+			runeIndex++
+		}
+	}
+
+	return Normalized{Text: builder.String(), Offsets: offsets, Ends: ends}
+}
@@ -0,0 +1,60 @@
+package filter_test
+
+import (
+	"testing"
+
+	"example.com/m/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		steps filter.NormalizeSteps
+		want  string
+	}{
+		{"full width", "ｆｕｃｋ", filter.NormalizeNFKC, "fuck"},
+		{"case fold", "FuCK", filter.NormalizeCaseFold, "fuck"},
+		{"zero width", "f\u200bu\u200dck", filter.NormalizeInvisible, "fuck"},
+		{"leetspeak", "5h1t", filter.NormalizeLeetspeak, "shit"},
+		{"cyrillic", "ѕһіt", filter.NormalizeHomoglyph, "shit"},
+		{"strikethrough", "b\u0336a\u0336d\u0336", filter.NormalizeMarks, "bad"},
+		{"accents", "bádé", filter.NormalizeNFKC | filter.NormalizeMarks, "bade"},
+		{"hangul unchanged", "나쁜", filter.NormalizeMarks, "나쁜"},
+		{"all steps", "ＳＨ\u200b１Т", filter.NormalizeAll, "shit"},
+		{"chinese unchanged", "混蛋", filter.NormalizeAll, "混蛋"},
+		{"disabled", "ＦＵＣＫ", 0, "ＦＵＣＫ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, filter.NormalizeText(tt.input, tt.steps).Text)
+		})
+	}
+}
+
+func TestNormalizedOriginalSpan(t *testing.T) {
+	// ﬁ 展開為兩個字元，零寬字元被移除
+	normalized := filter.NormalizeText("a\u200bﬁb", filter.NormalizeAll)
+	assert.Equal(t, "afib", normalized.Text)
+	assert.Equal(t, []int{0, 2, 2, 3}, normalized.Offsets)
+
+	start, end := normalized.OriginalSpan(1, 3)
+	assert.Equal(t, 2, start)
+	assert.Equal(t, 3, end)
+}
+
+func TestNormalizedOriginalSpanStripsMarks(t *testing.T) {
+	// 附加符號與前一個字元屬於同一片段，移除後每個字元仍對應到原文的基本字元
+	normalized := filter.NormalizeText("x b\u0336a\u0301d", filter.NormalizeAll)
+	assert.Equal(t, "x bad", normalized.Text)
+	assert.Equal(t, []int{0, 1, 2, 4, 6}, normalized.Offsets)
+
+	start, end := normalized.OriginalSpan(2, 5)
+	assert.Equal(t, 2, start)
+	assert.Equal(t, 7, end)
+}
+
+func TestParseNormalizeSteps(t *testing.T) {
+	assert.Equal(t, filter.NormalizeNFKC|filter.NormalizeLeetspeak, filter.ParseNormalizeSteps("NFKC, leetspeak, bogus"))
+}
@@ -0,0 +1,73 @@
+package filter
+
+import (
+	"fmt"
+)
+
+// Policy 房間的過濾設定，在全域詞庫之上調整分類、處理方式並加入房間專屬的詞
+type Policy struct {
+	Categories []string          `json:"categories,omitempty"` // 啟用的分類，空表示全部啟用
+	Actions    map[string]string `json:"actions,omitempty"`    // 分類的處理方式覆寫，allow 表示忽略該分類
+	Words      []Entry           `json:"words,omitempty"`      // 只在此房間生效的敏感詞
+	AllowWords []string          `json:"allowWords,omitempty"` // 在此房間放行的敏感詞
+}
+
+// Validate 檢查房間過濾設定中的分類與處理方式
+func (p *Policy) Validate() error {
+	for _, category := range p.Categories {
+		if !IsValidCategory(category) {
+			return fmt.Errorf("%w: unknown category %q", ErrInvalidEntry, category)
+		}
+	}
+	for category, action := range p.Actions {
+		if !IsValidCategory(category) {
+			return fmt.Errorf("%w: unknown category %q", ErrInvalidEntry, category)
+		}
+		if !IsValidAction(action) {
+			return fmt.Errorf("%w: invalid action %q for category %q", ErrInvalidEntry, action, category)
+		}
+	}
+	for _, entry := range p.Words {
+		if err := entry.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsEmpty 是否沒有任何房間設定
+func (p *Policy) IsEmpty() bool {
+	return p == nil || (len(p.Categories) == 0 && len(p.Actions) == 0 && len(p.Words) == 0 && len(p.AllowWords) == 0)
+}
+
+// categoryEnabled 分類是否在此房間啟用
+func (p *Policy) categoryEnabled(category string) bool {
+	if len(p.Categories) == 0 {
+		return true
+	}
+	for _, enabled := range p.Categories {
+		if enabled == category {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed 敏感詞是否在此房間放行，放行詞與敏感詞以相同的正規化比較
+func (p *Policy) allowed(word string, normalize func(string) string) bool {
+	for _, allow := range p.AllowWords {
+		if normalize(allow) == word {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveAction 決定命中在此房間的處理方式：房間的分類覆寫優先，包含詞本身設定的處理方式，
+// 房間才能收緊或放寬整個分類；沒有覆寫時依詞本身與全域分類的設定
+func (p *Policy) resolveAction(info WordInfo, categoryActions map[string]string) string {
+	if action, ok := p.Actions[info.Category]; ok {
+		return action
+	}
+	return info.ResolveAction(categoryActions)
+}
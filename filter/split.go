@@ -0,0 +1,139 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+)
+
+// GapSet 拆字檢測時允許夾在敏感詞字元之間的填充字元種類
+type GapSet uint8
+
+const (
+	GapWhitespace  GapSet = 1 << iota // 空白與換行
+	GapPunctuation                    // 標點符號與一般符號
+	GapZeroWidth                      // 零寬字元等不可見的格式字元
+	GapEmoji                          // emoji 與變體選擇符
+
+	GapAll = GapWhitespace | GapPunctuation | GapZeroWidth | GapEmoji
+)
+
+var gapNames = map[string]GapSet{
+	"whitespace":  GapWhitespace,
+	"punctuation": GapPunctuation,
+	"zerowidth":   GapZeroWidth,
+	"emoji":       GapEmoji,
+}
+
+// ParseGapSet 解析以逗號分隔的填充字元種類，例如 "whitespace,punctuation"，未知名稱會被忽略
+func ParseGapSet(value string) GapSet {
+	var gaps GapSet
+	for _, name := range strings.Split(value, ",") {
+		gaps |= gapNames[strings.ToLower(strings.TrimSpace(name))]
+	}
+	return gaps
+}
+
+// Contains 判斷字元是否屬於允許的填充字元
+func (g GapSet) Contains(r rune) bool {
+	switch {
+	case g&GapZeroWidth != 0 && isZeroWidth(r):
+		return true
+	case g&GapEmoji != 0 && isEmoji(r):
+		return true
+	case g&GapWhitespace != 0 && unicode.IsSpace(r):
+		return true
+	case g&GapPunctuation != 0 && (unicode.IsPunct(r) || unicode.IsSymbol(r)) && !isEmoji(r):
+		return true
+	}
+	return false
+}
+
+func isZeroWidth(r rune) bool {
+	return r == '\u200b' || r == '\u200c' || r == '\u200d' || r == '\u2060' || r == '\ufeff' || r == '\u00ad' || unicode.Is(unicode.Cf, r)
+}
+
+func isEmoji(r rune) bool {
+	return (r >= 0x1F000 && r <= 0x1FAFF) || // 各種 emoji、符號與象形文字
+		(r >= 0x2600 && r <= 0x27BF) || // 雜項符號與裝飾符號
+		(r >= 0xFE00 && r <= 0xFE0F) || // 變體選擇符
+		(r >= 0x1F3FB && r <= 0x1F3FF) // 膚色修飾符
+}
+
+// SplitOptions 拆字檢測設定
+type SplitOptions struct {
+	Gaps   GapSet // 允許的填充字元
+	MaxGap int    // 兩個字元之間最多允許的填充字元數
+}
+
+// DefaultSplitOptions 預設允許所有填充字元種類，每個間隔最多 3 個
+func DefaultSplitOptions() SplitOptions {
+	return SplitOptions{Gaps: GapAll, MaxGap: 3}
+}
+
+// splitState 拆字檢測中正在進行的匹配
+type splitState struct {
+	node   int32 // 目前所在的狀態
+	start  int   // 匹配開始的位置
+	gap    int   // 目前連續的填充字元數
+	gapped bool  // 匹配過程中是否出現過填充字元
+}
+
+// DetectSplit 以 Automaton 的字典樹檢測被填充字元拆開的敏感詞，例如 "s.h.i.t"，
+// 只回報中間確實夾有填充字元的命中；完整出現的敏感詞由 Find 處理。
+// 填充字元以外的字元會中斷匹配，所以不會跨越整段文字拼出敏感詞
+func (a *Automaton) DetectSplit(content string, options SplitOptions) []Match {
+	var matches []Match
+	if options.Gaps == 0 || options.MaxGap <= 0 {
+		return matches
+	}
+
+	var active, next []splitState
+	position := -1
+	for _, char := range content {
+		position++
+		next = next[:0]
+
+		if options.Gaps.Contains(char) {
+			// 填充字元：延長進行中的匹配，不會開始新的匹配
+			for _, state := range active {
+				if state.gap < options.MaxGap {
+					next = addSplitState(next, splitState{node: state.node, start: state.start, gap: state.gap + 1, gapped: true})
+				}
+			}
+		} else {
+			// 一般字元：推進進行中的匹配，並從根節點開始新的匹配
+			advance := func(state splitState) {
+				child := a.child(state.node, char)
+				if child < 0 {
+					return
+				}
+				if id := a.word[child]; id >= 0 && state.gapped {
+					matches = append(matches, Match{Word: a.patterns[id], Span: Span{Start: state.start, End: position + 1}, Split: true})
+				}
+				if a.hasChildren(child) {
+					next = addSplitState(next, splitState{node: child, start: state.start, gapped: state.gapped})
+				}
+			}
+			for _, state := range active {
+				advance(state)
+			}
+			advance(splitState{node: 0, start: position})
+		}
+
+		active, next = next, active
+	}
+	return matches
+}
+
+// addSplitState 加入狀態，相同節點與 gapped 的狀態只保留填充字元較少者，相同時保留較晚開始的匹配
+func addSplitState(states []splitState, state splitState) []splitState {
+	for i, existing := range states {
+		if existing.node == state.node && existing.gapped == state.gapped {
+			if state.gap < existing.gap || (state.gap == existing.gap && state.start > existing.start) {
+				states[i] = state
+			}
+			return states
+		}
+	}
+	return append(states, state)
+}
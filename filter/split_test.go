@@ -0,0 +1,123 @@
+package filter_test
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"example.com/m/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestMatcher(words ...string) *filter.Matcher {
+	matcher := filter.NewMatcher()
+	for _, word := range words {
+		matcher.Insert(word)
+	}
+	matcher.Build()
+	return matcher
+}
+
+func newTestAutomaton(words ...string) *filter.Automaton {
+	matcher := filter.NewMatcher()
+	for _, word := range words {
+		matcher.Insert(word)
+	}
+	return matcher.Compile()
+}
+
+// splitCounts 統計每個敏感詞的拆字命中次數
+func splitCounts(matches []filter.Match) map[string]int {
+	counts := make(map[string]int)
+	for _, m := range matches {
+		counts[m.Word]++
+	}
+	return counts
+}
+
+func TestDetectSplit(t *testing.T) {
+	automaton := newTestAutomaton("shit", "混蛋")
+	options := filter.SplitOptions{Gaps: filter.GapAll, MaxGap: 2}
+
+	tests := []struct {
+		name    string
+		message string
+		want    map[string]int
+	}{
+		{"whitespace", "s h i t", map[string]int{"shit": 1}},
+		{"punctuation", "s.h-i_t!", map[string]int{"shit": 1}},
+		{"zero width", "sh\u200bit", map[string]int{"shit": 1}},
+		{"emoji", "混😀蛋", map[string]int{"混蛋": 1}},
+		{"unsplit is left to Find", "shit", map[string]int{}},
+		{"gap too long", "s...h i t", map[string]int{}},
+		{"letters in between", "see how it turns", map[string]int{}},
+		{"across a paragraph", "so, here is the thing: it works", map[string]int{}},
+		{"twice", "s h i t and s.h.i.t", map[string]int{"shit": 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, splitCounts(automaton.DetectSplit(tt.message, options)))
+		})
+	}
+}
+
+func TestDetectSplitSpan(t *testing.T) {
+	automaton := newTestAutomaton("shit")
+	matches := automaton.DetectSplit("oh s h i t!", filter.SplitOptions{Gaps: filter.GapAll, MaxGap: 2})
+	assert.Equal(t, []filter.Match{{Word: "shit", Span: filter.Span{Start: 3, End: 10}, Split: true}}, matches)
+}
+
+func TestDetectSplitGapSet(t *testing.T) {
+	automaton := newTestAutomaton("shit")
+
+	onlySpaces := filter.SplitOptions{Gaps: filter.ParseGapSet("whitespace"), MaxGap: 3}
+	assert.Equal(t, map[string]int{"shit": 1}, splitCounts(automaton.DetectSplit("s h i t", onlySpaces)))
+	assert.Empty(t, automaton.DetectSplit("s.h.i.t", onlySpaces))
+
+	assert.Empty(t, automaton.DetectSplit("s h i t", filter.SplitOptions{Gaps: 0, MaxGap: 3}))
+	assert.Equal(t, filter.GapWhitespace|filter.GapEmoji, filter.ParseGapSet(" Whitespace ,emoji,unknown"))
+}
+
+// 基準測試使用的詞庫與訊息
+func benchmarkDictionary(n int) ([]string, string) {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = fmt.Sprintf("word%dx", i)
+	}
+	message := strings.Repeat("this is a perfectly normal chat message, w o r d 1 2 x included. ", 4)
+	return words, message
+}
+
+// splitRegex 舊版以正規表示式檢測拆字的做法，每次都為每個敏感詞編譯正規表示式，作為基準測試的對照
+func splitRegex(words []string, message string) map[string]int {
+	results := make(map[string]int)
+	for _, word := range words {
+		splitPattern := ""
+		for _, char := range word {
+			splitPattern += string(char) + ".*?"
+		}
+		if regexp.MustCompile(splitPattern).MatchString(message) {
+			results[word]++
+		}
+	}
+	return results
+}
+
+func BenchmarkSplitRegex(b *testing.B) {
+	words, message := benchmarkDictionary(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		splitRegex(words, message)
+	}
+}
+
+func BenchmarkSplitAutomaton(b *testing.B) {
+	words, message := benchmarkDictionary(1000)
+	automaton := newTestAutomaton(words...)
+	options := filter.SplitOptions{Gaps: filter.GapAll, MaxGap: 3}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		automaton.DetectSplit(message, options)
+	}
+}
@@ -0,0 +1,84 @@
+package filter
+
+import (
+	"unicode"
+)
+
+// 命中被排除的原因
+const (
+	SuppressedAllowList    = "allow-list"      // 命中落在允許詞之內
+	SuppressedWordBoundary = "word-boundary"   // 拉丁字母敏感詞不在單字邊界
+	SuppressedRoomAllow    = "room-allow"      // 房間放行的敏感詞
+	SuppressedRoomCategory = "room-category"   // 房間未啟用該分類
+	SuppressedRoomAction   = "room-action"     // 房間將該分類設為 allow
+	SuppressedCategory     = "category-action" // 分類的處理方式設為 allow
+)
+
+// SuppressedHit 被排除的命中，用於說明為何沒有處理
+type SuppressedHit struct {
+	Word   string `json:"word"`
+	Span   Span   `json:"span"`
+	Reason string `json:"reason"`
+	Detail string `json:"detail,omitempty"` // 例如排除此命中的允許詞
+}
+
+// suppress 排除落在允許詞之內，或不在單字邊界的命中；位置為正規化文字中的位置。
+// 單字邊界以原文判斷，避免 leetspeak 把標點（例如 "!"）轉成字母後誤判
+func (d *Dictionary) suppress(message string, normalized Normalized, matches []Match) ([]Match, []SuppressedHit) {
+	var allowed []Match
+	if d.allow != nil {
+		allowed = d.allow.Find(normalized.Text)
+	}
+
+	var runes []rune
+	if d.options.WordBoundary {
+		runes = []rune(message)
+	}
+
+	kept := matches[:0]
+	var suppressed []SuppressedHit
+	for _, m := range matches {
+		if term, ok := containingTerm(m.Span, allowed); ok {
+			suppressed = append(suppressed, SuppressedHit{Word: m.Word, Span: m.Span, Reason: SuppressedAllowList, Detail: term})
+			continue
+		}
+		if d.options.WordBoundary && !m.Split && isLatinWord(m.Word) && !atWordBoundary(runes, normalized.originalSpan(m.Span)) {
+			suppressed = append(suppressed, SuppressedHit{Word: m.Word, Span: m.Span, Reason: SuppressedWordBoundary})
+			continue
+		}
+		kept = append(kept, m)
+	}
+	return kept, suppressed
+}
+
+// containingTerm 返回包含此範圍的允許詞
+func containingTerm(span Span, allowed []Match) (string, bool) {
+	for _, term := range allowed {
+		if term.Span.Start <= span.Start && span.End <= term.Span.End {
+			return term.Word, true
+		}
+	}
+	return "", false
+}
+
+// isLatinWord 判斷敏感詞是否只由拉丁字母與數字組成
+func isLatinWord(word string) bool {
+	for _, char := range word {
+		if !unicode.Is(unicode.Latin, char) && !unicode.IsDigit(char) {
+			return false
+		}
+	}
+	return word != ""
+}
+
+// atWordBoundary 判斷範圍前後是否都不是字母或數字
+func atWordBoundary(runes []rune, span Span) bool {
+	isWordChar := func(char rune) bool { return unicode.IsLetter(char) || unicode.IsDigit(char) }
+	if span.Start > 0 && isWordChar(runes[span.Start-1]) {
+		return false
+	}
+	if span.End < len(runes) && isWordChar(runes[span.End]) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package filter
+
+import (
+	"sort"
+)
+
+// Hit 單一敏感詞的命中結果
+type Hit struct {
+	Word     string `json:"word"`
+	Category string `json:"category"`
+	Severity int    `json:"severity"`
+	Action   string `json:"action"`
+	Count    int    `json:"count"`
+	Split    bool   `json:"split"` // 是否只有拆字命中
+	Spans    []Span `json:"spans"` // 在原文中的位置
+}
+
+// Verdict Dictionary.Check 的結構化結果
+type Verdict struct {
+	Action      string          `json:"action"`      // 所有命中中最嚴格的處理方式
+	Content     string          `json:"content"`     // 遮蔽後的訊息內容
+	Original    string          `json:"-"`           // 原始訊息內容
+	MaxSeverity int             `json:"maxSeverity"` // 命中敏感詞的最高嚴重程度
+	Hits        []Hit           `json:"hits"`
+	Suppressed  []SuppressedHit `json:"suppressed,omitempty"` // 被允許詞、單字邊界或房間設定排除的命中
+}
+
+// Categories 返回命中的分類（不重複）
+func (v Verdict) Categories() []string {
+	seen := make(map[string]bool)
+	var categories []string
+	for _, hit := range v.Hits {
+		if !seen[hit.Category] {
+			seen[hit.Category] = true
+			categories = append(categories, hit.Category)
+		}
+	}
+	return categories
+}
+
+// newVerdict 依命中的敏感詞與其分類組合出處理結果，policy 不為 nil 時套用房間設定；
+// matches 的位置為原文中的位置
+func (d *Dictionary) newVerdict(message string, matches []Match, lookup func(string) WordInfo, policy *Policy) Verdict {
+	verdict := Verdict{Action: ActionAllow, Content: message, Original: message}
+	categoryActions := d.options.CategoryActions
+
+	// 依敏感詞分組，同一位置重複的命中（例如同時被拼音與原詞命中）只計一次
+	grouped := make(map[string][]Match)
+	seen := make(map[Span]map[string]bool)
+	for _, m := range matches {
+		if seen[m.Span] == nil {
+			seen[m.Span] = make(map[string]bool)
+		}
+		if seen[m.Span][m.Word] {
+			continue
+		}
+		seen[m.Span][m.Word] = true
+		grouped[m.Word] = append(grouped[m.Word], m)
+	}
+
+	for word, wordMatches := range grouped {
+		info := lookup(word)
+		action := info.ResolveAction(categoryActions)
+		reason := ""
+		if policy != nil {
+			action = policy.resolveAction(info, categoryActions)
+			switch {
+			case policy.allowed(word, d.normalizeWord):
+				reason = SuppressedRoomAllow
+			case !policy.categoryEnabled(info.Category):
+				reason = SuppressedRoomCategory
+			case action == ActionAllow:
+				reason = SuppressedRoomAction
+			}
+		}
+		if reason == "" && action == ActionAllow {
+			reason = SuppressedCategory
+		}
+		if reason != "" {
+			for _, m := range wordMatches {
+				verdict.Suppressed = append(verdict.Suppressed, SuppressedHit{Word: word, Span: m.Span, Reason: reason})
+			}
+			continue
+		}
+
+		hit := Hit{
+			Word:     word,
+			Category: info.Category,
+			Severity: info.Severity,
+			Action:   action,
+			Count:    len(wordMatches),
+			Split:    true,
+		}
+		for _, m := range wordMatches {
+			hit.Split = hit.Split && m.Split
+			hit.Spans = append(hit.Spans, m.Span)
+		}
+		sort.Slice(hit.Spans, func(i, j int) bool { return hit.Spans[i].Start < hit.Spans[j].Start })
+
+		verdict.Hits = append(verdict.Hits, hit)
+		verdict.Action = StricterAction(verdict.Action, hit.Action)
+		verdict.MaxSeverity = max(verdict.MaxSeverity, hit.Severity)
+	}
+
+	// 高嚴重程度的命中交由人工審核
+	if d.options.HoldSeverity > 0 && verdict.MaxSeverity >= d.options.HoldSeverity {
+		verdict.Action = StricterAction(verdict.Action, ActionHold)
+	}
+
+	// 固定順序，方便記錄與測試
+	sort.Slice(verdict.Hits, func(i, j int) bool { return verdict.Hits[i].Word < verdict.Hits[j].Word })
+	return verdict
+}
@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"example.com/m/config"
+	"example.com/m/filter"
+	"example.com/m/filter/importer"
+	"github.com/labstack/echo/v4"
+)
+
+// 詞庫上傳的大小上限
+const maxDictionaryUpload = 10 << 20
+
+// ListSensitiveWords 分頁列出敏感詞，支援 q 關鍵字搜尋
+func ListSensitiveWords(e echo.Context) error {
+	limit, err := strconv.Atoi(e.QueryParam("limit"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	offset, err := strconv.Atoi(e.QueryParam("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	words, total, err := config.ListSensitiveWords(e.QueryParam("q"), limit, offset)
+	if err != nil {
+		config.Logger.Error("Error listing sensitive words:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error listing sensitive words"})
+	}
+	return e.JSON(http.StatusOK, echo.Map{"words": words, "total": total})
+}
+
+// AddSensitiveWords 新增一個或多個敏感詞，body 為 {"word": "..."} 或 {"words": [...]}，
+// category、severity、action 會套用到所有新增的詞
+func AddSensitiveWords(e echo.Context) error {
+	var request struct {
+		Word     string   `json:"word"`
+		Words    []string `json:"words"`
+		Category string   `json:"category"`
+		Severity int      `json:"severity"`
+		Action   string   `json:"action"`
+	}
+	if err := e.Bind(&request); err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input"})
+	}
+
+	var entries []filter.Entry
+	for _, word := range append(request.Words, request.Word) {
+		entries = append(entries, filter.Entry{Word: word, Category: request.Category, Severity: request.Severity, Action: request.Action})
+	}
+
+	added, err := config.AddSensitiveWordEntries(entries, config.WordSourceAdmin)
+	if errors.Is(err, filter.ErrInvalidEntry) {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+	if err != nil {
+		config.Logger.Error("Error adding sensitive words:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error adding sensitive words"})
+	}
+	if added > 0 {
+		audit(e, config.AuditEntry{Action: "word.add", TargetType: auditTargetWord, Target: auditTargetList(append(request.Words, request.Word))},
+			nil, echo.Map{"entries": entries, "added": added})
+	}
+	return e.JSON(http.StatusOK, echo.Map{"added": added})
+}
+
+// UpdateSensitiveWord 更新敏感詞的分類、嚴重程度與處理方式，空值表示使用預設
+func UpdateSensitiveWord(e echo.Context) error {
+	word, err := url.PathUnescape(e.Param("word"))
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid word"})
+	}
+
+	var request filter.WordInfo
+	if err := e.Bind(&request); err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input"})
+	}
+
+	entry := filter.Entry{Word: word, Category: request.Category, Severity: request.Severity, Action: request.Action}
+	before, err := config.GetSensitiveWord(word)
+	if err != nil {
+		config.Logger.Error("Error fetching sensitive word:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error updating sensitive word"})
+	}
+	updated, err := config.UpdateSensitiveWord(entry)
+	if errors.Is(err, filter.ErrInvalidEntry) {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+	if err != nil {
+		config.Logger.Error("Error updating sensitive word:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error updating sensitive word"})
+	}
+	if !updated {
+		return e.JSON(http.StatusNotFound, echo.Map{"error": "Word not found"})
+	}
+	audit(e, config.AuditEntry{Action: "word.update", TargetType: auditTargetWord, Target: word}, before, entry.Info())
+	return e.JSON(http.StatusOK, echo.Map{"word": word, "info": entry.Info()})
+}
+
+// RemoveSensitiveWord 刪除敏感詞
+func RemoveSensitiveWord(e echo.Context) error {
+	word, err := url.PathUnescape(e.Param("word"))
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid word"})
+	}
+
+	before, err := config.GetSensitiveWord(word)
+	if err != nil {
+		config.Logger.Error("Error fetching sensitive word:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error removing sensitive word"})
+	}
+	removed, err := config.RemoveSensitiveWords([]string{word})
+	if err != nil {
+		config.Logger.Error("Error removing sensitive word:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error removing sensitive word"})
+	}
+	if removed == 0 {
+		return e.JSON(http.StatusNotFound, echo.Map{"error": "Word not found"})
+	}
+	audit(e, config.AuditEntry{Action: "word.remove", TargetType: auditTargetWord, Target: word}, before, nil)
+	return e.JSON(http.StatusOK, echo.Map{"removed": removed})
+}
+
+// ImportSensitiveWords 批次匯入敏感詞，body 為 JSON {"words": [...]}（元素可為 WordEntry）或每行一個詞的純文字
+func ImportSensitiveWords(e echo.Context) error {
+	var entries []filter.Entry
+	if strings.HasPrefix(e.Request().Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		var request struct {
+			Words []filter.Entry `json:"words"`
+		}
+		if err := e.Bind(&request); err != nil {
+			return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input"})
+		}
+		entries = request.Words
+	} else {
+		var err error
+		entries, err = importer.TXT{}.Import(io.LimitReader(e.Request().Body, maxDictionaryUpload))
+		if err != nil {
+			return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input"})
+		}
+	}
+
+	added, err := config.AddSensitiveWordEntries(entries, config.WordSourceAPI)
+	if errors.Is(err, filter.ErrInvalidEntry) {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+	if err != nil {
+		config.Logger.Error("Error importing sensitive words:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error importing sensitive words"})
+	}
+	audit(e, config.AuditEntry{Action: "dictionary.import", TargetType: auditTargetDictionary},
+		nil, echo.Map{"words": len(entries), "added": added})
+	return e.JSON(http.StatusOK, echo.Map{"added": added})
+}
+
+// UploadSensitiveWords 上傳詞庫檔案並匯入，支援 csv、txt、json、xlsx；
+// 格式預設依副檔名判斷，表格格式可用 sheet、column、header 欄位指定讀取方式
+func UploadSensitiveWords(e echo.Context) error {
+	fileHeader, err := e.FormFile("file")
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "File is required"})
+	}
+	if fileHeader.Size > maxDictionaryUpload {
+		return e.JSON(http.StatusRequestEntityTooLarge, echo.Map{"error": "File too large"})
+	}
+
+	options := importer.DefaultOptions()
+	options.Sheet = e.FormValue("sheet")
+	if column := e.FormValue("column"); column != "" {
+		if options.Column, err = strconv.Atoi(column); err != nil {
+			return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid column"})
+		}
+	}
+	if header := e.FormValue("header"); header != "" {
+		options.HasHeader = header == "true"
+	}
+
+	format := e.FormValue("format")
+	if format == "" {
+		format = filepath.Ext(fileHeader.Filename)
+	}
+	wordImporter, err := importer.New(format, options)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Could not read file"})
+	}
+	defer file.Close()
+
+	entries, err := wordImporter.Import(file)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid dictionary file: " + err.Error()})
+	}
+
+	added, err := config.AddSensitiveWordEntries(entries, config.WordSourceAPI)
+	if errors.Is(err, filter.ErrInvalidEntry) {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+	if err != nil {
+		config.Logger.Error("Error importing sensitive words:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error importing sensitive words"})
+	}
+	audit(e, config.AuditEntry{Action: "dictionary.upload", TargetType: auditTargetDictionary, Target: fileHeader.Filename},
+		nil, echo.Map{"words": len(entries), "added": added})
+	return e.JSON(http.StatusOK, echo.Map{"words": len(entries), "added": added})
+}
+
+// SyncSensitiveWordsFile 手動重新匯入詞庫檔案，force=true 時即使檔案未變更也會比對
+func SyncSensitiveWordsFile(e echo.Context) error {
+	force := e.QueryParam("force") == "true"
+	result, err := config.ImportSensitiveWordsFile(config.SensitiveWordsFile(), config.SensitiveWordsImportOptions(), force)
+	if err != nil {
+		config.Logger.Error("Error importing sensitive word file:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error importing sensitive word file"})
+	}
+
+	if !result.Skipped {
+		if err := config.NotifySensitiveWordsChanged(); err != nil {
+			config.Logger.Error("Error reloading sensitive words:", err)
+			return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error reloading sensitive words"})
+		}
+		audit(e, config.AuditEntry{Action: "dictionary.sync", TargetType: auditTargetDictionary, Target: config.SensitiveWordsFile()}, nil, result)
+	}
+	return e.JSON(http.StatusOK, result)
+}
+
+// ReloadSensitiveWords 強制所有實例重新加載詞庫
+func ReloadSensitiveWords(e echo.Context) error {
+	if err := config.NotifySensitiveWordsChanged(); err != nil {
+		config.Logger.Error("Error reloading sensitive words:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error reloading sensitive words"})
+	}
+	audit(e, config.AuditEntry{Action: "dictionary.reload", TargetType: auditTargetDictionary}, nil, nil)
+	return e.JSON(http.StatusOK, echo.Map{"status": "Reloaded"})
+}
+
+// ListAllowTerms 列出允許詞
+func ListAllowTerms(e echo.Context) error {
+	terms, err := config.ListAllowTerms()
+	if err != nil {
+		config.Logger.Error("Error listing allow-list:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error listing allow-list"})
+	}
+	return e.JSON(http.StatusOK, echo.Map{"terms": terms})
+}
+
+// AddAllowTerms 新增允許詞，body 為 {"term": "..."} 或 {"terms": [...]}
+func AddAllowTerms(e echo.Context) error {
+	var request struct {
+		Term  string   `json:"term"`
+		Terms []string `json:"terms"`
+	}
+	if err := e.Bind(&request); err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input"})
+	}
+
+	added, err := config.AddAllowTerms(append(request.Terms, request.Term), e.Get("username").(string))
+	if err != nil {
+		config.Logger.Error("Error adding allow-list terms:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error adding allow-list terms"})
+	}
+	if added > 0 {
+		audit(e, config.AuditEntry{Action: "allowlist.add", TargetType: auditTargetAllowTerm, Target: auditTargetList(append(request.Terms, request.Term))},
+			nil, echo.Map{"added": added})
+	}
+	return e.JSON(http.StatusOK, echo.Map{"added": added})
+}
+
+// RemoveAllowTerm 刪除允許詞
+func RemoveAllowTerm(e echo.Context) error {
+	term, err := url.PathUnescape(e.Param("term"))
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid term"})
+	}
+
+	removed, err := config.RemoveAllowTerms([]string{term})
+	if err != nil {
+		config.Logger.Error("Error removing allow-list term:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error removing allow-list term"})
+	}
+	if removed == 0 {
+		return e.JSON(http.StatusNotFound, echo.Map{"error": "Term not found"})
+	}
+	audit(e, config.AuditEntry{Action: "allowlist.remove", TargetType: auditTargetAllowTerm, Target: term}, echo.Map{"term": term}, nil)
+	return e.JSON(http.StatusOK, echo.Map{"removed": removed})
+}
+
+// TestSensitiveWords 以目前的詞庫測試訊息，返回每個命中的原因與被排除的命中；
+// 指定 room 時會套用該房間的過濾設定
+func TestSensitiveWords(e echo.Context) error {
+	var request struct {
+		Message string `json:"message"`
+		Room    string `json:"room"`
+	}
+	if err := e.Bind(&request); err != nil || request.Message == "" {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Message is required"})
+	}
+
+	var policy *filter.Policy
+	if request.Room != "" {
+		room, err := getRoom(request.Room)
+		if err != nil {
+			config.Logger.Error("Error fetching room:", err)
+			return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error fetching room"})
+		}
+		policy = &room.FilterPolicy
+	}
+
+	verdict := config.FilterMessageForRoom(request.Room, request.Message, policy)
+	return e.JSON(http.StatusOK, echo.Map{
+		"action":      verdict.Action,
+		"content":     verdict.Content,
+		"maxSeverity": verdict.MaxSeverity,
+		"hits":        explainHits(request.Message, verdict.Hits),
+		"suppressed":  verdict.Suppressed,
+		"allowList":   config.Ac.Load().AllowList(),
+	})
+}
+
+// explainHits 為每個命中附上原文中實際命中的文字
+func explainHits(message string, hits []filter.Hit) []echo.Map {
+	runes := []rune(message)
+	explained := make([]echo.Map, 0, len(hits))
+	for _, hit := range hits {
+		matched := make([]string, 0, len(hit.Spans))
+		for _, span := range hit.Spans {
+			matched = append(matched, string(runes[span.Start:span.End]))
+		}
+		explained = append(explained, echo.Map{
+			"word":     hit.Word,
+			"category": hit.Category,
+			"severity": hit.Severity,
+			"action":   hit.Action,
+			"split":    hit.Split,
+			"spans":    hit.Spans,
+			"matched":  matched,
+		})
+	}
+	return explained
+}
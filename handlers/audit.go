@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/m/config"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+	maxAuditExport    = 10000 // CSV 匯出一次最多的筆數
+)
+
+// 稽核紀錄的對象種類
+const (
+	auditTargetUser       = "user"
+	auditTargetMessage    = "message"
+	auditTargetModeration = "moderation"
+	auditTargetWord       = "word"
+	auditTargetAllowTerm  = "allow_term"
+	auditTargetDictionary = "dictionary"
+	auditTargetRoom       = "room"
+)
+
+// audit 記錄一筆稽核紀錄，before、after 為變更前後的狀態，會以 JSON 保存，nil 表示沒有。
+// e 為 nil 時表示系統自動執行的操作。記錄失敗只寫入錯誤日誌，不影響已完成的操作
+func audit(e echo.Context, entry config.AuditEntry, before, after interface{}) {
+	if e != nil {
+		if username, ok := e.Get("username").(string); ok && entry.Actor == "" {
+			entry.Actor = username
+		}
+		entry.RequestID = e.Response().Header().Get(echo.HeaderXRequestID)
+	}
+	if entry.Actor == "" {
+		entry.Actor = systemModerator
+	}
+
+	var err error
+	if entry.Before, err = auditJSON(before); err != nil {
+		config.Logger.Error("Error encoding audit state:", err)
+	}
+	if entry.After, err = auditJSON(after); err != nil {
+		config.Logger.Error("Error encoding audit state:", err)
+	}
+	if err := config.RecordAudit(&entry); err != nil {
+		config.Logger.Errorf("Error recording audit entry %s %s/%s by %s: %v", entry.Action, entry.TargetType, entry.Target, entry.Actor, err)
+	}
+}
+
+func auditJSON(state interface{}) (json.RawMessage, error) {
+	if state == nil {
+		return nil, nil
+	}
+	return json.Marshal(state)
+}
+
+// auditTargetList 將批次操作的多個對象合併為一個 target，以逗號分隔並略過空值
+func auditTargetList(values []string) string {
+	var targets []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			targets = append(targets, value)
+		}
+	}
+	return strings.Join(targets, ",")
+}
+
+// parseAuditFilter 讀取查詢參數中的篩選條件，since、until 為 RFC 3339 格式
+func parseAuditFilter(e echo.Context, maxLimit int) (config.AuditFilter, error) {
+	filter := config.AuditFilter{
+		Actor:      e.QueryParam("actor"),
+		Action:     e.QueryParam("action"),
+		TargetType: e.QueryParam("targetType"),
+		Target:     e.QueryParam("target"),
+		Room:       e.QueryParam("room"),
+		Limit:      min(defaultAuditLimit, maxLimit),
+	}
+
+	var err error
+	if value := e.QueryParam("since"); value != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, value); err != nil {
+			return filter, echo.NewHTTPError(http.StatusBadRequest, echo.Map{"error": "Invalid since"})
+		}
+	}
+	if value := e.QueryParam("until"); value != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, value); err != nil {
+			return filter, echo.NewHTTPError(http.StatusBadRequest, echo.Map{"error": "Invalid until"})
+		}
+	}
+	if value := e.QueryParam("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return filter, echo.NewHTTPError(http.StatusBadRequest, echo.Map{"error": "Invalid limit"})
+		}
+		filter.Limit = min(limit, maxLimit)
+	}
+	if value := e.QueryParam("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return filter, echo.NewHTTPError(http.StatusBadRequest, echo.Map{"error": "Invalid offset"})
+		}
+		filter.Offset = offset
+	}
+	return filter, nil
+}
+
+// ListAuditLog 由新到舊列出稽核紀錄，可依 actor、action（或前綴）、targetType、target、room、since、until 篩選；
+// format=csv 時以 CSV 檔案匯出，最多 10000 筆
+func ListAuditLog(e echo.Context) error {
+	export := e.QueryParam("format") == "csv"
+	maxLimit := maxAuditLimit
+	if export {
+		maxLimit = maxAuditExport
+	}
+
+	filter, err := parseAuditFilter(e, maxLimit)
+	if err != nil {
+		return err
+	}
+	if export && e.QueryParam("limit") == "" {
+		filter.Limit = maxAuditExport
+	}
+
+	entries, total, err := config.ListAuditEntries(filter)
+	if err != nil {
+		config.Logger.Error("Error fetching audit log:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error fetching audit log"})
+	}
+
+	if !export {
+		return e.JSON(http.StatusOK, echo.Map{"entries": entries, "total": total})
+	}
+
+	filename := "audit-" + time.Now().UTC().Format("20060102-150405") + ".csv"
+	e.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	e.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	e.Response().Header().Set("X-Total-Count", strconv.Itoa(total))
+	e.Response().WriteHeader(http.StatusOK)
+	return config.WriteAuditCSV(e.Response(), entries)
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
@@ -110,17 +111,26 @@ func LoginUser(e echo.Context) error {
 	}
 
 	var storedHash string
-	err := config.PgConn.QueryRow(config.Ctx, "SELECT password FROM users WHERE username=$1", user.Username).Scan(&storedHash)
+	var roles []string
+	err := config.PgConn.QueryRow(config.Ctx, "SELECT password, roles FROM users WHERE username=$1", user.Username).Scan(&storedHash, &roles)
 	if err != nil || bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(user.Password)) != nil {
 		return e.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid username or password"})
 	}
 
+	// 被封鎖的用戶不能登入
+	var sanctionErr *sanctionError
+	if err := checkBanned(user.Username); errors.As(err, &sanctionErr) {
+		return e.JSON(http.StatusForbidden, echo.Map{"error": sanctionErr.Error(), "reason": sanctionErr.Sanction.Reason, "expiresAt": sanctionErr.Sanction.ExpiresAt})
+	} else if err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]string{"error": "Error checking account status"})
+	}
+
 	_, err = config.PgConn.Exec(config.Ctx, "UPDATE users SET time = NOW() WHERE username = $1", user.Username)
 	if err != nil {
 		return e.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update login time"})
 	}
 
-	token, err := middlewares.GenerateJWT(user.Username)
+	token, err := middlewares.GenerateJWT(user.Username, roles...)
 	if err != nil {
 		return e.JSON(http.StatusInternalServerError, map[string]string{"error": "Error generating token"})
 	}
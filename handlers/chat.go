@@ -10,6 +10,9 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// visibleMessage 过滤已过期或因检举被隐藏的消息的 SQL 条件，所有读取历史消息的查询都必须加上
+const visibleMessage = "(expires_at IS NULL OR expires_at > NOW()) AND NOT hidden"
+
 // 获取聊天记录
 func GetChatHistory(e echo.Context) error {
 	room := e.QueryParam("room")
@@ -37,7 +40,7 @@ func GetChatHistory(e echo.Context) error {
 	}
 
 	// 查询聊天记录
-	rows, err := config.PgConn.Query(config.Ctx, "SELECT sender, content, time FROM chat_messages WHERE room = $1 AND time >= $2 AND time < $3 ORDER BY time ASC", room, startDate, endDate)
+	rows, err := config.PgConn.Query(config.Ctx, "SELECT id, sender, content, time, expires_at, ARRAY(SELECT a.id FROM attachments a WHERE a.message_id = chat_messages.id ORDER BY a.id) FROM chat_messages WHERE room = $1 AND time >= $2 AND time < $3 AND "+visibleMessage+" ORDER BY time ASC", room, startDate, endDate)
 	if err != nil {
 		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error fetching chat history"})
 	}
@@ -46,7 +49,7 @@ func GetChatHistory(e echo.Context) error {
 	var messages []config.ChatMessage
 	for rows.Next() {
 		var msg config.ChatMessage
-		if err := rows.Scan(&msg.Sender, &msg.Content, &msg.Time); err != nil {
+		if err := rows.Scan(&msg.ID, &msg.Sender, &msg.Content, &msg.Time, &msg.ExpiresAt, &msg.Attachments); err != nil {
 			return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error scanning message"})
 		}
 		msg.Room = room
@@ -75,7 +78,7 @@ func GetLatestChatDate(e echo.Context) error {
 	currentDate := time.Now()
 
 	// 查询数据库中最早的聊天记录日期
-	err := config.PgConn.QueryRow(config.Ctx, "SELECT MIN(time) FROM chat_messages WHERE room = $1", room).Scan(&earliestDate)
+	err := config.PgConn.QueryRow(config.Ctx, "SELECT MIN(time) FROM chat_messages WHERE room = $1 AND "+visibleMessage, room).Scan(&earliestDate)
 	if err != nil {
 		config.Logger.Error("Error fetching earliest chat date:", err)
 		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error fetching earliest chat date"})
@@ -102,9 +105,9 @@ func GetLatestChatDate(e echo.Context) error {
 	for {
 		// 查询指定日期和房间的聊天记录
 		rows, err := config.PgConn.Query(config.Ctx, `
-			SELECT * 
+			SELECT id, room, sender, content, time, expires_at
 			FROM chat_messages 
-			WHERE DATE(time) = $1 AND room = $2 
+			WHERE DATE(time) = $1 AND room = $2 AND `+visibleMessage+`
 			ORDER BY time ASC
 		`, currentDate.Format("2006-01-02"), room)
 		if err != nil {
@@ -117,7 +120,7 @@ func GetLatestChatDate(e echo.Context) error {
 		var dailyMessages []config.ChatMessage
 		for rows.Next() {
 			var message config.ChatMessage
-			if err := rows.Scan(&message.ID, &message.Room, &message.Sender, &message.Content, &message.Time); err != nil { // 根据你的结构体字段调整
+			if err := rows.Scan(&message.ID, &message.Room, &message.Sender, &message.Content, &message.Time, &message.ExpiresAt); err != nil { // 根据你的结构体字段调整
 				config.Logger.Error("Error scanning message:", err)
 				return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error scanning message"})
 			}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	"example.com/m/config"
+	"github.com/jackc/pgx/v5"
+)
+
+// messageExpiry 計算訊息的過期時間：取訊息自帶的 expiresIn 與房間預設保留時間中較早者，皆未設定時回傳 nil
+func messageExpiry(roomInfo *config.Room, expiresIn int64, sentAt time.Time) *time.Time {
+	ttl := time.Duration(expiresIn) * time.Second
+	retention := time.Duration(roomInfo.RetentionSeconds) * time.Second
+	if ttl <= 0 || (retention > 0 && retention < ttl) {
+		ttl = retention
+	}
+	if ttl <= 0 {
+		return nil
+	}
+
+	expiresAt := sentAt.Add(ttl)
+	return &expiresAt
+}
+
+// StartMessageJanitor 啟動背景 goroutine，定期清除過期訊息
+func StartMessageJanitor(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := purgeExpiredMessages(); err != nil {
+				log.Println("Error purging expired messages:", err)
+			}
+		}
+	}()
+}
+
+// purgeExpiredMessages 刪除過期訊息與其附件，並廣播 messageExpired 事件讓客戶端移除
+func purgeExpiredMessages() error {
+	tx, err := config.PgConn.Begin(config.Ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(config.Ctx)
+
+	// 先鎖定一次過期訊息的編號，附件與訊息都只刪除這些編號，避免兩次 NOW() 之間過期的訊息留下附件；
+	// SKIP LOCKED 保證多個實例同時執行時每則訊息只會被一個實例處理
+	rows, err := tx.Query(config.Ctx, "SELECT id FROM chat_messages WHERE expires_at <= NOW() FOR UPDATE SKIP LOCKED")
+	if err != nil {
+		return err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	// 附件的 message_id 在訊息刪除後會被設為 NULL，所以要先刪除附件
+	rows, err = tx.Query(config.Ctx, `
+		DELETE FROM attachments WHERE message_id = ANY($1)
+		RETURNING storage_key, COALESCE(thumbnail_key, '')`, ids)
+	if err != nil {
+		return err
+	}
+	var keys []string
+	for rows.Next() {
+		var storageKey, thumbnailKey string
+		if err := rows.Scan(&storageKey, &thumbnailKey); err != nil {
+			rows.Close()
+			return err
+		}
+		keys = append(keys, storageKey)
+		if thumbnailKey != "" {
+			keys = append(keys, thumbnailKey)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = tx.Query(config.Ctx, "DELETE FROM chat_messages WHERE id = ANY($1) RETURNING id, room", ids)
+	if err != nil {
+		return err
+	}
+	expired := make(map[string][]int64)
+	for rows.Next() {
+		var id int64
+		var room string
+		if err := rows.Scan(&id, &room); err != nil {
+			rows.Close()
+			return err
+		}
+		expired[room] = append(expired[room], id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := tx.Commit(config.Ctx); err != nil {
+		return err
+	}
+
+	// 交易提交後才刪除檔案，交易失敗時附件仍完整保留
+	for _, key := range keys {
+		if err := config.FileStorage.Delete(config.Ctx, key); err != nil {
+			log.Println("Error deleting expired attachment:", err)
+		}
+	}
+
+	for room, ids := range expired {
+		BroadcastEventToRoom(room, map[string]interface{}{
+			"type":       "messageExpired",
+			"messageIds": ids,
+		})
+		// 每個房間每次清除只記錄一筆摘要
+		audit(nil, config.AuditEntry{
+			Action:     "message.expire",
+			TargetType: auditTargetMessage,
+			Room:       room,
+		}, nil, map[string]interface{}{"count": len(ids), "messageIds": ids})
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+
+	"example.com/m/config"
+	"example.com/m/linkpreview"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	maxPreviewsPerMessage = 3
+	linkPreviewTTL        = 24 * time.Hour
+	linkPreviewFailureTTL = 10 * time.Minute // 抓取失敗時也快取，避免重複抓取同一個壞連結
+)
+
+var previewFetcher = linkpreview.NewFetcher()
+
+// publishLinkPreviews 抓取訊息中連結的預覽資訊並推送 linkPreview 事件到房間，應在訊息儲存後以 goroutine 呼叫
+func publishLinkPreviews(message config.ChatMessage) {
+	for _, url := range linkpreview.ExtractURLs(message.Content, maxPreviewsPerMessage) {
+		preview, err := getLinkPreview(url)
+		if err != nil {
+			log.Printf("Error fetching link preview for %s: %v", url, err)
+			continue
+		}
+		if preview == nil {
+			continue
+		}
+
+		BroadcastEventToRoom(message.Room, map[string]interface{}{
+			"type":      "linkPreview",
+			"messageId": message.ID,
+			"preview":   preview,
+		})
+	}
+}
+
+// getLinkPreview 先從 Redis 快取讀取預覽，沒有時才實際抓取；回傳 nil 表示該連結沒有可用的預覽
+func getLinkPreview(url string) (*linkpreview.Preview, error) {
+	sum := sha256.Sum256([]byte(url))
+	cacheKey := "link_preview:" + hex.EncodeToString(sum[:])
+
+	cached, err := config.RedisClient.Get(config.Ctx, cacheKey).Result()
+	if err == nil {
+		if cached == "" {
+			return nil, nil
+		}
+		var preview linkpreview.Preview
+		if err := json.Unmarshal([]byte(cached), &preview); err == nil {
+			return &preview, nil
+		}
+	} else if err != redis.Nil {
+		log.Println("Error reading link preview cache:", err)
+	}
+
+	ctx, cancel := context.WithTimeout(config.Ctx, previewFetcher.Timeout)
+	defer cancel()
+
+	preview, err := previewFetcher.Fetch(ctx, url)
+	if err != nil || preview.Title == "" {
+		config.RedisClient.Set(config.Ctx, cacheKey, "", linkPreviewFailureTTL)
+		return nil, err
+	}
+
+	data, err := json.Marshal(preview)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.RedisClient.Set(config.Ctx, cacheKey, data, linkPreviewTTL).Err(); err != nil {
+		log.Println("Error caching link preview:", err)
+	}
+	return preview, nil
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"example.com/m/config"
+	"example.com/m/filter"
+	"example.com/m/metrics"
+	"example.com/m/moderation"
+	"example.com/m/spam"
+)
+
+// filterError 消息被敏感词过滤拒绝或暂缓发布
+type filterError struct {
+	Verdict filter.Verdict
+	QueueID int64 // 暂缓发布时审核队列中的编号
+}
+
+func (e *filterError) Error() string {
+	return fmt.Sprintf("message %s by sensitive word filter (%d hits)", actionPastTense(e.Verdict.Action), len(e.Verdict.Hits))
+}
+
+func (e *filterError) queueID() int64 { return e.QueueID }
+
+// frameType 返回通知发送者的消息类型
+func (e *filterError) frameType() string {
+	if e.Verdict.Action == filter.ActionHold {
+		return "messageHeld"
+	}
+	return "messageRejected"
+}
+
+func actionPastTense(action string) string {
+	if action == filter.ActionHold {
+		return "held"
+	}
+	return "rejected"
+}
+
+// reviewError 被敏感词过滤、垃圾消息评分或外部审核拒绝或暂缓发布的消息，queueID 不为 0 时表示已放入审核队列
+type reviewError interface {
+	error
+	queueID() int64
+}
+
+// publishMessage 聊天消息的发布流程：过滤、垃圾消息评分与外部审核 → 保存 → 广播，WebSocket 与排程消息共用
+func publishMessage(username string, message config.ChatMessage, expiresIn int64, attachments []int64) (*config.ChatMessage, error) {
+	message.Sender = username
+
+	// 被封锁、禁言或踢出房间的用户不能发送消息；排程消息与其他实例上尚未断开的连接也经由此处检查
+	if err := checkBanned(username); err != nil {
+		return nil, err
+	}
+	if err := checkMuted(message.Room, username); err != nil {
+		return nil, err
+	}
+	if err := checkKicked(message.Room, username); err != nil {
+		return nil, err
+	}
+	// 只有房间成员能发送消息，加入房间经由 WebSocket 的 join
+	member, err := isRoomMember(message.Room, username)
+	if err != nil {
+		return nil, err
+	}
+	if !member {
+		return nil, errNotRoomMember
+	}
+
+	// 读取房间设定：过滤设定与预设保留时间
+	roomInfo, err := getRoom(message.Room)
+	if err != nil {
+		return nil, err
+	}
+
+	verdict := config.FilterMessageForRoom(message.Room, message.Content, &roomInfo.FilterPolicy)
+	metrics.FilterVerdictCounter.WithLabelValues(verdict.Action).Inc()
+	held := newHeldItem(username, message, verdict, expiresIn, attachments)
+
+	// 已被拒绝的消息不需要再评分或送审
+	var spamResult spam.Result
+	if verdict.Action != filter.ActionReject {
+		spamResult = scoreSpam(username, message)
+		held.Signals = spamResult.Signals
+	}
+	var hookVerdict moderation.Verdict
+	if verdict.Action != filter.ActionReject && spamResult.Action != filter.ActionReject && syncModerationHook() {
+		hookVerdict = moderateMessage(username, message)
+		held.Classifier = &hookVerdict
+	}
+
+	switch {
+	case verdict.Action == filter.ActionReject:
+		log.Printf("Message from %s in %s rejected by filter: %v", username, message.Room, verdict.Categories())
+		return nil, &filterError{Verdict: verdict}
+	case spamResult.Action == filter.ActionReject:
+		log.Printf("Message from %s in %s rejected as spam (score %g): %v", username, message.Room, spamResult.Score, spamResult.Rules())
+		return nil, &spamError{Result: spamResult}
+	case hookVerdict.Action == filter.ActionReject:
+		log.Printf("Message from %s in %s rejected by moderation hook: %v", username, message.Room, hookVerdict.Categories)
+		return nil, &hookError{Verdict: hookVerdict}
+
+	// 暂缓发布的消息放入审核队列，由版主决定是否发布
+	case verdict.Action == filter.ActionHold:
+		held.Reason = moderationReasonFilter
+		item, err := holdMessage(held)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &filterError{Verdict: verdict, QueueID: item.ID}
+	case spamResult.Action == filter.ActionHold:
+		held.Reason = moderationReasonSpam
+		item, err := holdMessage(held)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &spamError{Result: spamResult, QueueID: item.ID}
+	case hookVerdict.Action == filter.ActionHold:
+		held.Reason = moderationReasonHook
+		item, err := holdMessage(held)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &hookError{Verdict: hookVerdict, QueueID: item.ID}
+
+	case verdict.Action == filter.ActionFlag:
+		log.Printf("Message from %s in %s flagged by filter: %v", username, message.Room, verdict.Categories())
+		message.Flagged = true
+	case hookVerdict.Action == filter.ActionFlag:
+		log.Printf("Message from %s in %s flagged by moderation hook: %v", username, message.Room, hookVerdict.Categories)
+		message.Flagged = true
+	}
+	message.Content = verdict.Content // 使用过滤后的消息内容
+
+	if err := deliverMessage(username, &message, roomInfo, expiresIn, attachments); err != nil {
+		return nil, err
+	}
+
+	// 非同步审核在发布后才送出，不阻塞消息处理
+	if asyncModerationHook() {
+		go moderatePublishedMessage(message)
+	}
+	return &message, nil
+}
+
+// deliverMessage 保存并广播已通过过滤的消息，审核通过的消息也经由此处发布
+func deliverMessage(username string, message *config.ChatMessage, roomInfo *config.Room, expiresIn int64, attachments []int64) error {
+	message.ExpiresAt = messageExpiry(roomInfo, expiresIn, time.Now())
+
+	if err := saveMessageToDB(message); err != nil {
+		return err
+	}
+
+	if err := linkAttachments(message, username, attachments); err != nil {
+		log.Println("Error linking attachments:", err)
+	}
+
+	BroadcastMessageToRoom(message.Room, *message)
+
+	// 非同步抓取连结预览，不阻塞消息处理
+	go publishLinkPreviews(*message)
+
+	return nil
+}
+
+func saveMessageToDB(message *config.ChatMessage) error {
+	return config.PgConn.QueryRow(config.Ctx, "INSERT INTO chat_messages (room, sender, content, time, expires_at, flagged) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		message.Room, message.Sender, message.Content, message.Time, message.ExpiresAt, message.Flagged).Scan(&message.ID)
+}
@@ -0,0 +1,428 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/m/config"
+	"example.com/m/filter"
+	"example.com/m/metrics"
+	"example.com/m/middlewares"
+	"example.com/m/spam"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// 消息进入审核队列的原因
+const (
+	moderationReasonFilter = "filter" // 敏感词过滤结果为 hold
+	moderationReasonReport = "report" // 用户检举，消息已发布
+	moderationReasonSpam   = "spam"   // 垃圾消息评分达到送审门槛
+	moderationReasonHook   = "hook"   // 外部审核服务的结果为 hold，或非同步审核时为 hold 或 reject
+)
+
+// 审核队列的状态
+const (
+	moderationPending  = "pending"
+	moderationApproved = "approved"
+	moderationRejected = "rejected"
+)
+
+const (
+	defaultModerationLimit = 50
+	maxModerationLimit     = 200
+)
+
+var errModerationItemNotFound = errors.New("Moderation item not found or already reviewed")
+
+const moderationColumns = `id, room, sender, content, original, reason, categories, severity, hits, signals, classifier, attachments, expires_in, message_time,
+	status, message_id, COALESCE(reviewed_by, ''), reviewed_at, note, created_at`
+
+func scanModerationItem(row pgx.Row) (*config.ModerationItem, error) {
+	var item config.ModerationItem
+	err := row.Scan(&item.ID, &item.Room, &item.Sender, &item.Content, &item.Original, &item.Reason, &item.Categories, &item.Severity, &item.Hits, &item.Signals, &item.Classifier,
+		&item.Attachments, &item.ExpiresIn, &item.Time, &item.Status, &item.MessageID, &item.ReviewedBy, &item.ReviewedAt, &item.Note, &item.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errModerationItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// newHeldItem 依過濾結果建立待審核的項目。審核通過後發布遮蔽後的內容，原始內容只保存給版主查看
+func newHeldItem(username string, message config.ChatMessage, verdict filter.Verdict, expiresIn int64, attachments []int64) config.ModerationItem {
+	return config.ModerationItem{
+		Room:        message.Room,
+		Sender:      username,
+		Content:     verdict.Content,
+		Original:    verdict.Original,
+		Categories:  verdict.Categories(),
+		Severity:    verdict.MaxSeverity,
+		Hits:        verdict.Hits,
+		Attachments: attachments,
+		ExpiresIn:   expiresIn,
+		Time:        message.Time,
+	}
+}
+
+// holdMessage 将消息放入审核队列，保存过滤结果、触发的垃圾消息规则与外部审核结果
+func holdMessage(held config.ModerationItem) (*config.ModerationItem, error) {
+	if held.Hits == nil {
+		held.Hits = []filter.Hit{}
+	}
+	if held.Signals == nil {
+		held.Signals = []spam.Signal{}
+	}
+	if held.Categories == nil {
+		held.Categories = []string{}
+	}
+
+	item, err := scanModerationItem(config.PgConn.QueryRow(config.Ctx, `
+		INSERT INTO moderation_queue (room, sender, content, original, reason, categories, severity, hits, signals, classifier, attachments, expires_in, message_time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING `+moderationColumns,
+		held.Room, held.Sender, held.Content, held.Original, held.Reason, held.Categories, held.Severity, held.Hits, held.Signals, held.Classifier,
+		held.Attachments, held.ExpiresIn, held.Time))
+	if err != nil {
+		return nil, err
+	}
+	metrics.ModerationCounter.WithLabelValues("held").Inc()
+	log.Printf("Message from %s in %s held for review by %s (#%d)", item.Sender, item.Room, item.Reason, item.ID)
+	return item, nil
+}
+
+// alreadyPublished 被检举的消息与非同步审核的消息在进入队列前已经发布，审核时只需隐藏或恢复
+func alreadyPublished(item *config.ModerationItem) bool {
+	return item.Reason == moderationReasonReport || (item.Reason == moderationReasonHook && item.MessageID != nil)
+}
+
+// getModerationItem 读取审核队列中的消息
+func getModerationItem(id int64) (*config.ModerationItem, error) {
+	return scanModerationItem(config.PgConn.QueryRow(config.Ctx, "SELECT "+moderationColumns+" FROM moderation_queue WHERE id = $1", id))
+}
+
+// listModerationItems 依进入队列的时间由旧到新列出房间的审核队列，reason 为空时列出所有原因
+func listModerationItems(room, status, reason string, limit int) ([]config.ModerationItem, error) {
+	rows, err := config.PgConn.Query(config.Ctx, "SELECT "+moderationColumns+` FROM moderation_queue
+		WHERE room = $1 AND status = $2 AND ($3 = '' OR reason = $3)
+		ORDER BY created_at, id
+		LIMIT $4`, room, status, reason, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []config.ModerationItem{}
+	for rows.Next() {
+		item, err := scanModerationItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range items {
+		if err := loadReportDetails(&items[i]); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// loadReportDetails 为已发布的项目附上检举内容与前后文
+func loadReportDetails(item *config.ModerationItem) error {
+	if !alreadyPublished(item) || item.MessageID == nil {
+		return nil
+	}
+
+	var err error
+	if item.Reports, err = listMessageReports(*item.MessageID); err != nil {
+		return err
+	}
+	item.Context, err = messageContext(item.Room, *item.MessageID, config.Moderation.ReportContext)
+	return err
+}
+
+// reviewModerationItem 将待审核的消息标记为已审核，同一则消息只能被审核一次
+func reviewModerationItem(id int64, status, moderator, note string) (*config.ModerationItem, error) {
+	return scanModerationItem(config.PgConn.QueryRow(config.Ctx, `
+		UPDATE moderation_queue SET status = $2, reviewed_by = $3, reviewed_at = NOW(), note = $4
+		WHERE id = $1 AND status = 'pending'
+		RETURNING `+moderationColumns, id, status, moderator, note))
+}
+
+// approveModerationItem 审核通过并经由一般的发布流程广播消息，content 不为空时以编辑后的内容发布；
+// 已发布的消息审核通过时取消隐藏（content 不为空时更新内容）
+func approveModerationItem(id int64, moderator, content string) (*config.ModerationItem, *config.ChatMessage, error) {
+	item, err := reviewModerationItem(id, moderationApproved, moderator, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	if alreadyPublished(item) {
+		if item.MessageID != nil {
+			err = restoreMessage(*item.MessageID, content)
+		}
+		if err == nil {
+			metrics.ModerationCounter.WithLabelValues(moderationApproved).Inc()
+			return item, nil, nil
+		}
+	} else {
+		var message *config.ChatMessage
+		if message, err = publishApprovedMessage(item, content); err == nil {
+			metrics.ModerationCounter.WithLabelValues(moderationApproved).Inc()
+			return item, message, nil
+		}
+	}
+
+	// 发布失败时放回队列，让版主可以重试
+	if _, resetErr := config.PgConn.Exec(config.Ctx, "UPDATE moderation_queue SET status = 'pending', reviewed_by = NULL, reviewed_at = NULL WHERE id = $1", id); resetErr != nil {
+		config.Logger.Error("Error resetting moderation item:", resetErr)
+	}
+	return nil, nil, err
+}
+
+// publishApprovedMessage 經由一般的發布流程廣播暫緩發布的消息（遮蔽後的內容），並記錄發布後的消息編號
+func publishApprovedMessage(item *config.ModerationItem, content string) (*config.ChatMessage, error) {
+	roomInfo, err := getRoom(item.Room)
+	if err != nil {
+		return nil, err
+	}
+
+	message := config.ChatMessage{Room: item.Room, Sender: item.Sender, Content: item.Content, Time: time.Now()}
+	if content != "" {
+		message.Content = content
+	}
+	if err := deliverMessage(item.Sender, &message, roomInfo, item.ExpiresIn, item.Attachments); err != nil {
+		return nil, err
+	}
+
+	messageID := int64(message.ID)
+	item.MessageID = &messageID
+	if _, err := config.PgConn.Exec(config.Ctx, "UPDATE moderation_queue SET message_id = $2 WHERE id = $1", item.ID, messageID); err != nil {
+		config.Logger.Error("Error linking moderation item to message:", err)
+	}
+	return &message, nil
+}
+
+// rejectModerationItem 拒绝发布并以 messageRejected 通知发送者，已发布的消息会被隐藏
+func rejectModerationItem(id int64, moderator, note string) (*config.ModerationItem, error) {
+	item, err := reviewModerationItem(id, moderationRejected, moderator, note)
+	if err != nil {
+		return nil, err
+	}
+	if alreadyPublished(item) && item.MessageID != nil {
+		if err := hideMessage(*item.MessageID); err != nil {
+			config.Logger.Error("Error hiding reported message:", err)
+		}
+	}
+	metrics.ModerationCounter.WithLabelValues(moderationRejected).Inc()
+
+	frame := map[string]interface{}{
+		"type":       "messageRejected",
+		"room":       item.Room,
+		"time":       item.Time,
+		"queueId":    item.ID,
+		"error":      "message rejected by moderator",
+		"note":       item.Note,
+		"categories": item.Categories,
+		"severity":   item.Severity,
+	}
+	if item.MessageID != nil {
+		frame["messageId"] = *item.MessageID
+	}
+	sendToUser(item.Sender, frame)
+	return item, nil
+}
+
+// moderationAuditState 审核项目写入稽核记录的状态
+func moderationAuditState(item *config.ModerationItem) map[string]interface{} {
+	state := map[string]interface{}{
+		"status":  item.Status,
+		"sender":  item.Sender,
+		"content": item.Content,
+		"reason":  item.Reason,
+	}
+	if item.MessageID != nil {
+		state["messageId"] = *item.MessageID
+	}
+	if item.Note != "" {
+		state["note"] = item.Note
+	}
+	return state
+}
+
+// auditModeration 记录版主审核的稽核记录
+func auditModeration(e echo.Context, action string, item *config.ModerationItem, before, after interface{}) {
+	audit(e, config.AuditEntry{
+		Action:     action,
+		TargetType: auditTargetModeration,
+		Target:     strconv.FormatInt(item.ID, 10),
+		Room:       item.Room,
+		Reason:     item.Reason,
+	}, before, after)
+}
+
+// moderationItemForReview 读取路径中的审核项目并确认目前用户为该房间的版主
+func moderationItemForReview(e echo.Context) (*config.ModerationItem, error) {
+	id, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, echo.Map{"error": "Invalid queue item ID"})
+	}
+
+	item, err := getModerationItem(id)
+	if errors.Is(err, errModerationItemNotFound) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, echo.Map{"error": err.Error()})
+	}
+	if err != nil {
+		config.Logger.Error("Error fetching moderation item:", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"error": "Error fetching moderation item"})
+	}
+	if err := requireRoomModerator(e, item.Room); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// requireIndependentReviewer 房間版主不能審核自己的消息，全域管理員與版主除外
+func requireIndependentReviewer(e echo.Context, item *config.ModerationItem) error {
+	if item.Sender == e.Get("username").(string) && !middlewares.HasRole(e, config.RoleAdmin, config.RoleModerator) {
+		return echo.NewHTTPError(http.StatusForbidden, echo.Map{"error": "Cannot review your own message"})
+	}
+	return nil
+}
+
+// GetModerationItem 取得審核項目，檢舉的項目會附上檢舉內容與前後文
+func GetModerationItem(e echo.Context) error {
+	item, err := moderationItemForReview(e)
+	if err != nil {
+		return err
+	}
+	if err := loadReportDetails(item); err != nil {
+		config.Logger.Error("Error fetching report details:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error fetching moderation item"})
+	}
+	return e.JSON(http.StatusOK, item)
+}
+
+// ListModerationQueue 列出房間的審核佇列，預設只列出待審核的消息
+func ListModerationQueue(e echo.Context) error {
+	room := e.QueryParam("room")
+	if room == "" {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Missing room"})
+	}
+	if err := requireRoomModerator(e, room); err != nil {
+		return err
+	}
+
+	status := e.QueryParam("status")
+	switch status {
+	case "":
+		status = moderationPending
+	case moderationPending, moderationApproved, moderationRejected:
+	default:
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid status"})
+	}
+
+	reason := e.QueryParam("reason")
+	switch reason {
+	case "", moderationReasonFilter, moderationReasonSpam, moderationReasonHook, moderationReasonReport:
+	default:
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid reason"})
+	}
+
+	limit := defaultModerationLimit
+	if value := e.QueryParam("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid limit"})
+		}
+		limit = min(parsed, maxModerationLimit)
+	}
+
+	items, err := listModerationItems(room, status, reason, limit)
+	if err != nil {
+		config.Logger.Error("Error fetching moderation queue:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error fetching moderation queue"})
+	}
+	return e.JSON(http.StatusOK, echo.Map{"items": items})
+}
+
+// ApproveModerationItem 審核通過並發布消息，可附上 content 以編輯後的內容發布
+func ApproveModerationItem(e echo.Context) error {
+	item, err := moderationItemForReview(e)
+	if err != nil {
+		return err
+	}
+	if err := requireIndependentReviewer(e, item); err != nil {
+		return err
+	}
+
+	var request struct {
+		Content *string `json:"content"`
+	}
+	if err := e.Bind(&request); err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input"})
+	}
+	content := ""
+	if request.Content != nil {
+		if content = strings.TrimSpace(*request.Content); content == "" {
+			return e.JSON(http.StatusBadRequest, echo.Map{"error": "Content cannot be empty"})
+		}
+	}
+
+	before := moderationAuditState(item)
+	item, message, err := approveModerationItem(item.ID, e.Get("username").(string), content)
+	if errors.Is(err, errModerationItemNotFound) {
+		return e.JSON(http.StatusConflict, echo.Map{"error": err.Error()})
+	}
+	if err != nil {
+		config.Logger.Error("Error approving moderation item:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error approving message"})
+	}
+	after := moderationAuditState(item)
+	if content != "" {
+		after["content"] = content
+	}
+	auditModeration(e, "moderation.approve", item, before, after)
+	return e.JSON(http.StatusOK, echo.Map{"item": item, "message": message})
+}
+
+// RejectModerationItem 拒絕發布消息，note 會一併通知發送者
+func RejectModerationItem(e echo.Context) error {
+	item, err := moderationItemForReview(e)
+	if err != nil {
+		return err
+	}
+	if err := requireIndependentReviewer(e, item); err != nil {
+		return err
+	}
+
+	var request struct {
+		Note string `json:"note"`
+	}
+	if err := e.Bind(&request); err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input"})
+	}
+
+	before := moderationAuditState(item)
+	item, err = rejectModerationItem(item.ID, e.Get("username").(string), strings.TrimSpace(request.Note))
+	if errors.Is(err, errModerationItemNotFound) {
+		return e.JSON(http.StatusConflict, echo.Map{"error": err.Error()})
+	}
+	if err != nil {
+		config.Logger.Error("Error rejecting moderation item:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error rejecting message"})
+	}
+	auditModeration(e, "moderation.reject", item, before, moderationAuditState(item))
+	return e.JSON(http.StatusOK, echo.Map{"item": item})
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"example.com/m/config"
+	"example.com/m/filter"
+	"example.com/m/metrics"
+	"example.com/m/moderation"
+)
+
+// hookError 消息被外部审核服务拒绝或暂缓发布
+type hookError struct {
+	Verdict moderation.Verdict
+	QueueID int64 // 暂缓发布时审核队列中的编号
+}
+
+func (e *hookError) Error() string {
+	if e.Verdict.Failed {
+		return fmt.Sprintf("message %s because the moderation service is unavailable", actionPastTense(e.Verdict.Action))
+	}
+	return fmt.Sprintf("message %s by moderation service", actionPastTense(e.Verdict.Action))
+}
+
+func (e *hookError) queueID() int64 { return e.QueueID }
+
+// frame 通知发送者的 WebSocket 消息，与敏感词过滤使用相同的消息类型
+func (e *hookError) frame(room, msgTime string) map[string]interface{} {
+	frameType := "messageRejected"
+	if e.Verdict.Action == filter.ActionHold {
+		frameType = "messageHeld"
+	}
+	frame := map[string]interface{}{
+		"type":       frameType,
+		"room":       room,
+		"time":       msgTime,
+		"error":      e.Error(),
+		"categories": e.Verdict.Categories,
+	}
+	if e.QueueID != 0 {
+		frame["queueId"] = e.QueueID
+	}
+	return frame
+}
+
+// syncModerationHook 是否在发布前等待外部审核的结果
+func syncModerationHook() bool {
+	return config.ModerationHook != nil && !config.ModerationHook.Async()
+}
+
+// asyncModerationHook 是否在发布后才送交外部审核
+func asyncModerationHook() bool {
+	return config.ModerationHook != nil && config.ModerationHook.Async()
+}
+
+// callModerationHook 呼叫外部审核服务并记录指标；服务失败时返回依 MODERATION_HOOK_ON_FAILURE 决定的结果
+func callModerationHook(request moderation.Request) moderation.Verdict {
+	hook := config.ModerationHook
+	start := time.Now()
+	verdict, err := hook.Moderate(config.Ctx, request)
+	metrics.ModerationHookDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.ModerationHookErrors.Inc()
+		log.Printf("Error calling moderation hook, applying %s: %v", verdict.Action, err)
+	}
+	metrics.ModerationHookCounter.WithLabelValues(hook.Mode, verdict.Action).Inc()
+	return verdict
+}
+
+// moderateMessage 发布前送交外部审核
+func moderateMessage(username string, message config.ChatMessage) moderation.Verdict {
+	return callModerationHook(moderation.Request{
+		Room:    message.Room,
+		Sender:  username,
+		Content: message.Content,
+		Time:    message.Time,
+	})
+}
+
+// moderatePublishedMessage 发布后送交外部审核，应以 goroutine 呼叫。结果为 hold 或 reject 时隐藏消息并放入审核队列，
+// 由版主决定是否恢复；结果为 flag 时标记消息
+func moderatePublishedMessage(message config.ChatMessage) {
+	verdict := callModerationHook(moderation.Request{
+		Room:      message.Room,
+		Sender:    message.Sender,
+		Content:   message.Content,
+		Time:      message.Time,
+		MessageID: int64(message.ID),
+	})
+
+	switch verdict.Action {
+	case filter.ActionHold, filter.ActionReject:
+		if err := hideMessage(int64(message.ID)); err != nil {
+			log.Println("Error hiding moderated message:", err)
+		} else {
+			audit(nil, config.AuditEntry{
+				Action:     "message.hide",
+				TargetType: auditTargetMessage,
+				Target:     strconv.Itoa(message.ID),
+				Room:       message.Room,
+				Reason:     moderationReasonHook,
+			}, nil, verdict)
+		}
+		if err := queueModeratedMessage(message, verdict); err != nil {
+			log.Println("Error queueing moderated message:", err)
+		}
+	case filter.ActionFlag:
+		if _, err := config.PgConn.Exec(config.Ctx, "UPDATE chat_messages SET flagged = TRUE WHERE id = $1", message.ID); err != nil {
+			log.Println("Error flagging moderated message:", err)
+		}
+	}
+}
+
+// queueModeratedMessage 将已发布的消息连同外部审核结果放入审核队列
+func queueModeratedMessage(message config.ChatMessage, verdict moderation.Verdict) error {
+	categories := verdict.Categories
+	if categories == nil {
+		categories = []string{}
+	}
+	_, err := config.PgConn.Exec(config.Ctx, `
+		INSERT INTO moderation_queue (room, sender, content, reason, categories, classifier, message_time, message_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		message.Room, message.Sender, message.Content, moderationReasonHook, categories, verdict, message.Time, message.ID)
+	if err != nil {
+		return err
+	}
+	metrics.ModerationCounter.WithLabelValues("held").Inc()
+	return nil
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"example.com/m/config"
+	"example.com/m/metrics"
+	"example.com/m/ratelimit"
+)
+
+// 自動禁言的處分者名稱
+const systemModerator = "system"
+
+var connectionSeq atomic.Int64
+
+// newConnectionID 產生 WebSocket 連線的識別碼，用於每個連線的令牌桶
+func newConnectionID() string {
+	return fmt.Sprintf("%s-%d", config.InstanceID, connectionSeq.Add(1))
+}
+
+// rateLimitError 訊息因發送頻率過高被拒絕
+type rateLimitError struct {
+	Scope      string // 令牌不足的範圍：user、room 或 connection
+	RetryAfter time.Duration
+}
+
+func (e *rateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded for %s, retry after %s", e.Scope, e.RetryAfter)
+}
+
+// frame 通知發送者的 WebSocket 消息，retryAfter 為秒數（無條件進位）
+func (e *rateLimitError) frame(room string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "rateLimited",
+		"room":       room,
+		"scope":      e.Scope,
+		"error":      e.Error(),
+		"retryAfter": int(math.Ceil(e.RetryAfter.Seconds())),
+	}
+}
+
+// checkRateLimit 檢查用戶、房間與連線的令牌桶，超過限制時回傳 rateLimitError 並累計違規次數。
+// Redis 發生錯誤時放行，避免限流器故障導致無法聊天
+func checkRateLimit(connectionID, username, room string) error {
+	settings := config.RateLimits
+	result, err := config.RateLimiter.Take(config.Ctx,
+		ratelimit.Limit{Key: "user:" + username, Bucket: settings.User},
+		ratelimit.Limit{Key: "room:" + room, Bucket: settings.Room},
+		ratelimit.Limit{Key: "connection:" + connectionID, Bucket: settings.Connection},
+	)
+	if err != nil {
+		log.Println("Error checking rate limit:", err)
+		return nil
+	}
+	if result.Allowed {
+		return nil
+	}
+
+	scope, _, _ := strings.Cut(result.Key, ":")
+	metrics.RateLimitedCounter.WithLabelValues(scope).Inc()
+	// 房間的令牌桶由所有成員共用，用完時不是個別用戶的責任，只累計用戶與連線範圍的違規
+	if scope != "room" {
+		if err := recordRateLimitViolation(username, room); err != nil {
+			log.Println("Error recording rate limit violation:", err)
+		}
+	}
+	return &rateLimitError{Scope: scope, RetryAfter: result.RetryAfter}
+}
+
+// recordRateLimitViolation 累計用戶在房間內被限制的次數，在 MuteWindow 內達到 MuteAfter 次時自動禁言
+func recordRateLimitViolation(username, room string) error {
+	settings := config.RateLimits
+	if settings.MuteAfter <= 0 {
+		return nil
+	}
+
+	key := "ratelimit:violations:" + room + ":" + username
+	count, err := config.RedisClient.Incr(config.Ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if count == 1 {
+		if err := config.RedisClient.Expire(config.Ctx, key, settings.MuteWindow).Err(); err != nil {
+			return err
+		}
+	}
+	if count < int64(settings.MuteAfter) {
+		return nil
+	}
+
+	if err := config.RedisClient.Del(config.Ctx, key).Err(); err != nil {
+		return err
+	}
+	// 已被禁言時不重複禁言，避免縮短版主設定的禁言時間
+	if err := checkMuted(room, username); errors.As(err, new(*sanctionError)) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	sanction := config.Sanction{
+		Kind:      sanctionMute,
+		Username:  username,
+		Room:      room,
+		Reason:    "Sending messages too quickly",
+		CreatedBy: systemModerator,
+	}
+	if err := issueSanction(&sanction, settings.MuteDuration); err != nil {
+		return err
+	}
+	log.Printf("User %s muted in %s for %s after repeated rate limit violations", username, room, settings.MuteDuration)
+	auditSanction(nil, "sanction."+sanctionMute, sanction, nil, sanction)
+	broadcastSanction("userMuted", sanction)
+	return nil
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"example.com/m/config"
+	"example.com/m/metrics"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const maxReportReasonLength = 500
+
+var (
+	errAlreadyReported = errors.New("You have already reported this message")
+	errMessageNotFound = errors.New("Message not found")
+)
+
+// reportMessage 記錄檢舉，同一用戶對同一則訊息只能檢舉一次；返回最近一次審核後不同檢舉者的數量
+func reportMessage(messageID int64, reporter, reason string) (int, error) {
+	tag, err := config.PgConn.Exec(config.Ctx, `
+		INSERT INTO message_reports (message_id, reporter, reason) VALUES ($1, $2, $3)
+		ON CONFLICT (message_id, reporter) DO NOTHING`, messageID, reporter, reason)
+	if err != nil {
+		return 0, err
+	}
+	if tag.RowsAffected() == 0 {
+		return 0, errAlreadyReported
+	}
+
+	// 只計算最近一次審核之後的檢舉，版主核准或恢復後需要重新累積到門檻才會再次隱藏
+	var count int
+	err = config.PgConn.QueryRow(config.Ctx, `
+		SELECT COUNT(*) FROM message_reports
+		WHERE message_id = $1 AND created_at > COALESCE(
+			(SELECT MAX(reviewed_at) FROM moderation_queue WHERE message_id = $1 AND status <> 'pending'), '-infinity')`,
+		messageID).Scan(&count)
+	return count, err
+}
+
+// queueReportedMessage 將被檢舉的訊息放入審核佇列，同一則訊息只會有一筆待審核的項目
+func queueReportedMessage(message config.ChatMessage) error {
+	tag, err := config.PgConn.Exec(config.Ctx, `
+		INSERT INTO moderation_queue (room, sender, content, reason, message_time, message_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (message_id) WHERE reason = 'report' AND status = 'pending' DO NOTHING`,
+		message.Room, message.Sender, message.Content, moderationReasonReport, message.Time, message.ID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() > 0 {
+		metrics.ModerationCounter.WithLabelValues("reported").Inc()
+	}
+	return nil
+}
+
+// hideMessage 隱藏訊息並廣播 messageHidden 事件讓客戶端移除，已隱藏的訊息不重複廣播
+func hideMessage(messageID int64) error {
+	var room string
+	err := config.PgConn.QueryRow(config.Ctx, "UPDATE chat_messages SET hidden = TRUE WHERE id = $1 AND NOT hidden RETURNING room", messageID).Scan(&room)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	metrics.ModerationCounter.WithLabelValues("hidden").Inc()
+	BroadcastEventToRoom(room, map[string]interface{}{
+		"type":      "messageHidden",
+		"messageId": messageID,
+	})
+	return nil
+}
+
+// restoreMessage 取消隱藏訊息，content 不為空時一併更新內容；內容或顯示狀態有變更時廣播 messageRestored 事件
+func restoreMessage(messageID int64, content string) error {
+	var wasHidden bool
+	err := config.PgConn.QueryRow(config.Ctx, "SELECT hidden FROM chat_messages WHERE id = $1", messageID).Scan(&wasHidden)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errMessageNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var message config.ChatMessage
+	err = config.PgConn.QueryRow(config.Ctx, `
+		UPDATE chat_messages SET hidden = FALSE, content = COALESCE(NULLIF($2, ''), content) WHERE id = $1
+		RETURNING id, room, sender, content, time, expires_at`, messageID, content).
+		Scan(&message.ID, &message.Room, &message.Sender, &message.Content, &message.Time, &message.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	if wasHidden || content != "" {
+		BroadcastEventToRoom(message.Room, map[string]interface{}{
+			"type":    "messageRestored",
+			"message": message,
+		})
+	}
+	return nil
+}
+
+// listMessageReports 列出訊息的檢舉，由舊到新
+func listMessageReports(messageID int64) ([]config.MessageReport, error) {
+	rows, err := config.PgConn.Query(config.Ctx, "SELECT id, message_id, reporter, reason, created_at FROM message_reports WHERE message_id = $1 ORDER BY created_at, id", messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []config.MessageReport{}
+	for rows.Next() {
+		var report config.MessageReport
+		if err := rows.Scan(&report.ID, &report.MessageID, &report.Reporter, &report.Reason, &report.CreatedAt); err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// messageContext 返回訊息本身與同一房間前後各 n 則未過期的訊息，依時間排序；包含被隱藏的訊息
+func messageContext(room string, messageID int64, n int) ([]config.ChatMessage, error) {
+	rows, err := config.PgConn.Query(config.Ctx, `
+		SELECT id, sender, content, time, expires_at, hidden FROM (
+			(SELECT * FROM chat_messages WHERE room = $1 AND id < $2 AND (expires_at IS NULL OR expires_at > NOW()) ORDER BY id DESC LIMIT $3)
+			UNION ALL
+			(SELECT * FROM chat_messages WHERE id = $2)
+			UNION ALL
+			(SELECT * FROM chat_messages WHERE room = $1 AND id > $2 AND (expires_at IS NULL OR expires_at > NOW()) ORDER BY id LIMIT $3)
+		) context
+		ORDER BY id`, room, messageID, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []config.ChatMessage{}
+	for rows.Next() {
+		message := config.ChatMessage{Room: room}
+		if err := rows.Scan(&message.ID, &message.Sender, &message.Content, &message.Time, &message.ExpiresAt, &message.Hidden); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+// ReportMessage 檢舉訊息，body 為 {"reason": "..."}。不同檢舉者達到 REPORT_HIDE_THRESHOLD 時訊息會被自動隱藏
+func ReportMessage(e echo.Context) error {
+	messageID, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid message ID"})
+	}
+
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if err := e.Bind(&request); err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input"})
+	}
+	reason := strings.TrimSpace(request.Reason)
+	if reason == "" || len([]rune(reason)) > maxReportReasonLength {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Reason is required and must be at most 500 characters"})
+	}
+
+	message := config.ChatMessage{ID: int(messageID)}
+	err = config.PgConn.QueryRow(config.Ctx, "SELECT room, sender, content, time FROM chat_messages WHERE id = $1 AND "+visibleMessage, messageID).
+		Scan(&message.Room, &message.Sender, &message.Content, &message.Time)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return e.JSON(http.StatusNotFound, echo.Map{"error": errMessageNotFound.Error()})
+	}
+	if err != nil {
+		config.Logger.Error("Error fetching message:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error reporting message"})
+	}
+
+	reporter := e.Get("username").(string)
+	if message.Sender == reporter {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "You cannot report your own message"})
+	}
+	member, err := isRoomMember(message.Room, reporter)
+	if err != nil {
+		config.Logger.Error("Error checking room membership:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error reporting message"})
+	}
+	if !member {
+		return e.JSON(http.StatusForbidden, echo.Map{"error": "Not a member of this room"})
+	}
+
+	count, err := reportMessage(messageID, reporter, reason)
+	if errors.Is(err, errAlreadyReported) {
+		return e.JSON(http.StatusConflict, echo.Map{"error": err.Error()})
+	}
+	if err != nil {
+		config.Logger.Error("Error reporting message:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error reporting message"})
+	}
+
+	if err := queueReportedMessage(message); err != nil {
+		config.Logger.Error("Error queueing reported message:", err)
+	}
+
+	hidden := false
+	if threshold := config.Moderation.ReportHideThreshold; threshold > 0 && count >= threshold {
+		if err := hideMessage(messageID); err != nil {
+			config.Logger.Error("Error hiding reported message:", err)
+		} else {
+			hidden = true
+			audit(e, config.AuditEntry{
+				Actor:      systemModerator,
+				Action:     "message.hide",
+				TargetType: auditTargetMessage,
+				Target:     strconv.FormatInt(messageID, 10),
+				Room:       message.Room,
+				Reason:     moderationReasonReport,
+			}, nil, echo.Map{"reports": count, "threshold": threshold})
+		}
+	}
+
+	return e.JSON(http.StatusOK, echo.Map{"status": "Message reported", "reports": count, "hidden": hidden})
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"example.com/m/config"
+	"github.com/labstack/echo/v4"
+)
+
+// GetUserRoles 取得用戶的全域角色
+func GetUserRoles(e echo.Context) error {
+	username := e.Param("username")
+	roles, err := config.UserRoles(username)
+	if errors.Is(err, config.ErrUserNotFound) {
+		return e.JSON(http.StatusNotFound, echo.Map{"error": "User not found"})
+	}
+	if err != nil {
+		config.Logger.Error("Error fetching user roles:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error fetching user roles"})
+	}
+	return e.JSON(http.StatusOK, echo.Map{"username": username, "roles": roles})
+}
+
+// GrantUserRole 授予用戶角色，body 為 {"role": "moderator"}；下一個請求起即生效，不需重新登入
+func GrantUserRole(e echo.Context) error {
+	var request struct {
+		Role string `json:"role"`
+	}
+	if err := e.Bind(&request); err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input"})
+	}
+	return changeUserRole(e, "role.grant", request.Role, config.GrantRole)
+}
+
+// RevokeUserRole 撤銷用戶角色，不能撤銷最後一位管理員；已簽發的 token 在下一個請求起即失去該角色
+func RevokeUserRole(e echo.Context) error {
+	return changeUserRole(e, "role.revoke", e.Param("role"), config.RevokeRole)
+}
+
+// changeUserRole 授予或撤銷角色並記錄稽核紀錄
+func changeUserRole(e echo.Context, action, value string, change func(username, role string) ([]string, []string, error)) error {
+	username := e.Param("username")
+	role, err := config.ParseRole(value)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	before, after, err := change(username, role)
+	switch {
+	case errors.Is(err, config.ErrUserNotFound):
+		return e.JSON(http.StatusNotFound, echo.Map{"error": "User not found"})
+	case errors.Is(err, config.ErrMemberRole):
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	case errors.Is(err, config.ErrLastAdmin):
+		return e.JSON(http.StatusConflict, echo.Map{"error": err.Error()})
+	case err != nil:
+		config.Logger.Error("Error changing user role:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error changing user role"})
+	}
+
+	audit(e, config.AuditEntry{Action: action, TargetType: auditTargetUser, Target: username}, before, after)
+	return e.JSON(http.StatusOK, echo.Map{"username": username, "roles": after})
+}
@@ -0,0 +1,466 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"example.com/m/config"
+	"example.com/m/filter"
+	"example.com/m/middlewares"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	errMessageNotInRoom = errors.New("Message not found in this room")
+	errNotRoomModerator = errors.New("Only room moderators can do this")
+	errNotRoomOwner     = errors.New("Only the room owner can do this")
+)
+
+// joinRoom 將用戶加入房間成員。只有實際建立新房間的用戶成為擁有者：rooms 的主鍵保證同時加入時只有一個
+// INSERT 會成功；已存在的房間（包含部署前已有訊息的房間）不會從空的成員名單推定擁有者，由管理員指定。
+// 被踢出房間且尚未到期的用戶回傳 sanctionError
+func joinRoom(room, username string) error {
+	if err := checkKicked(room, username); err != nil {
+		return err
+	}
+
+	tx, err := config.PgConn.Begin(config.Ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(config.Ctx)
+
+	role := "member"
+	var created string
+	err = tx.QueryRow(config.Ctx, "INSERT INTO rooms (name, created_by) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING RETURNING name", room, username).Scan(&created)
+	if err == nil {
+		role = "owner"
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	_, err = tx.Exec(config.Ctx, `
+		INSERT INTO room_members (room, username, role) VALUES ($1, $2, $3)
+		ON CONFLICT (room, username) DO NOTHING`, room, username, role)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(config.Ctx)
+}
+
+// setRoomOwner 指定房間擁有者，原本的擁有者改為版主；房間或成員不存在時一併建立
+func setRoomOwner(room, username string) error {
+	tx, err := config.PgConn.Begin(config.Ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(config.Ctx)
+
+	if _, err := tx.Exec(config.Ctx, "INSERT INTO rooms (name) VALUES ($1) ON CONFLICT (name) DO NOTHING", room); err != nil {
+		return err
+	}
+	_, err = tx.Exec(config.Ctx, "UPDATE room_members SET role = 'moderator' WHERE room = $1 AND role = 'owner' AND username <> $2", room, username)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(config.Ctx, `
+		INSERT INTO room_members (room, username, role) VALUES ($1, $2, 'owner')
+		ON CONFLICT (room, username) DO UPDATE SET role = 'owner'`, room, username)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(config.Ctx)
+}
+
+// leaveRoom 將用戶移出房間成員
+func leaveRoom(room, username string) error {
+	_, err := config.PgConn.Exec(config.Ctx, "DELETE FROM room_members WHERE room = $1 AND username = $2", room, username)
+	return err
+}
+
+// isRoomMember 檢查用戶是否為房間成員
+func isRoomMember(room, username string) (bool, error) {
+	var exists bool
+	err := config.PgConn.QueryRow(config.Ctx, "SELECT EXISTS (SELECT 1 FROM room_members WHERE room = $1 AND username = $2)", room, username).Scan(&exists)
+	return exists, err
+}
+
+// roomMembers 讀取房間所有成員的用戶名，廣播時只送給這些用戶的連線
+func roomMembers(room string) (map[string]bool, error) {
+	rows, err := config.PgConn.Query(config.Ctx, "SELECT username FROM room_members WHERE room = $1", room)
+	if err != nil {
+		return nil, err
+	}
+	usernames, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[string]bool, len(usernames))
+	for _, username := range usernames {
+		members[username] = true
+	}
+	return members, nil
+}
+
+// isRoomModerator 檢查用戶是否為房間擁有者或版主
+func isRoomModerator(room, username string) (bool, error) {
+	var exists bool
+	err := config.PgConn.QueryRow(config.Ctx, "SELECT EXISTS (SELECT 1 FROM room_members WHERE room = $1 AND username = $2 AND role IN ('owner', 'moderator'))", room, username).Scan(&exists)
+	return exists, err
+}
+
+// isRoomOwner 檢查用戶是否為房間擁有者
+func isRoomOwner(room, username string) (bool, error) {
+	var exists bool
+	err := config.PgConn.QueryRow(config.Ctx, "SELECT EXISTS (SELECT 1 FROM room_members WHERE room = $1 AND username = $2 AND role = 'owner')", room, username).Scan(&exists)
+	return exists, err
+}
+
+// getRoom 讀取房間資訊，房間尚未建立時回傳只有名稱的房間
+func getRoom(room string) (*config.Room, error) {
+	result := config.Room{Name: room}
+	err := config.PgConn.QueryRow(config.Ctx, "SELECT announcement, COALESCE(announcement_by, ''), announcement_at, retention_seconds, filter_policy FROM rooms WHERE name = $1", room).
+		Scan(&result.Announcement, &result.AnnouncementBy, &result.AnnouncementTime, &result.RetentionSeconds, &result.FilterPolicy)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// setAnnouncement 更新房間公告並廣播 announcement 事件，空字串表示清除公告
+func setAnnouncement(room, announcement, username string) (*config.Room, error) {
+	result := config.Room{Name: room, Announcement: announcement, AnnouncementBy: username}
+	err := config.PgConn.QueryRow(config.Ctx, `
+		INSERT INTO rooms (name, announcement, announcement_by, announcement_at) VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (name) DO UPDATE SET announcement = EXCLUDED.announcement, announcement_by = EXCLUDED.announcement_by, announcement_at = EXCLUDED.announcement_at
+		RETURNING announcement_at, retention_seconds`, room, announcement, username).Scan(&result.AnnouncementTime, &result.RetentionSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	BroadcastEventToRoom(room, announcementEvent(&result))
+	return &result, nil
+}
+
+func announcementEvent(room *config.Room) map[string]interface{} {
+	return map[string]interface{}{
+		"type":             "announcement",
+		"announcement":     room.Announcement,
+		"announcementBy":   room.AnnouncementBy,
+		"announcementTime": room.AnnouncementTime,
+	}
+}
+
+// listRoomPins 依置頂時間由新到舊列出房間的置頂訊息
+func listRoomPins(room string) ([]config.RoomPin, error) {
+	rows, err := config.PgConn.Query(config.Ctx, `
+		SELECT p.pinned_by, p.pinned_at, m.id, m.sender, m.content, m.time
+		FROM room_pins p JOIN chat_messages m ON m.id = p.message_id
+		WHERE p.room = $1 AND (m.expires_at IS NULL OR m.expires_at > NOW()) AND NOT m.hidden
+		ORDER BY p.pinned_at DESC`, room)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pins := []config.RoomPin{}
+	for rows.Next() {
+		pin := config.RoomPin{Room: room}
+		if err := rows.Scan(&pin.PinnedBy, &pin.PinnedAt, &pin.Message.ID, &pin.Message.Sender, &pin.Message.Content, &pin.Message.Time); err != nil {
+			return nil, err
+		}
+		pin.Message.Room = room
+		pins = append(pins, pin)
+	}
+	return pins, rows.Err()
+}
+
+// pinMessage 置頂房間內的訊息並廣播 messagePinned 事件，重複置頂不視為錯誤
+func pinMessage(room string, messageID int64, username string) (*config.RoomPin, error) {
+	pin := config.RoomPin{Room: room, PinnedBy: username}
+	err := config.PgConn.QueryRow(config.Ctx, "SELECT id, sender, content, time FROM chat_messages WHERE id = $1 AND room = $2 AND "+visibleMessage, messageID, room).
+		Scan(&pin.Message.ID, &pin.Message.Sender, &pin.Message.Content, &pin.Message.Time)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errMessageNotInRoom
+	}
+	if err != nil {
+		return nil, err
+	}
+	pin.Message.Room = room
+
+	err = config.PgConn.QueryRow(config.Ctx, `
+		INSERT INTO room_pins (room, message_id, pinned_by) VALUES ($1, $2, $3)
+		ON CONFLICT (room, message_id) DO UPDATE SET pinned_by = room_pins.pinned_by
+		RETURNING pinned_by, pinned_at`, room, messageID, username).Scan(&pin.PinnedBy, &pin.PinnedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	BroadcastEventToRoom(room, map[string]interface{}{
+		"type": "messagePinned",
+		"pin":  pin,
+	})
+	return &pin, nil
+}
+
+// unpinMessage 取消置頂並廣播 messageUnpinned 事件
+func unpinMessage(room string, messageID int64, username string) error {
+	tag, err := config.PgConn.Exec(config.Ctx, "DELETE FROM room_pins WHERE room = $1 AND message_id = $2", room, messageID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errMessageNotInRoom
+	}
+
+	BroadcastEventToRoom(room, map[string]interface{}{
+		"type":       "messageUnpinned",
+		"messageId":  messageID,
+		"unpinnedBy": username,
+	})
+	return nil
+}
+
+// requireRoomModerator 確認目前用戶為房間版主，或擁有全域的 admin、moderator 角色，否則回傳對應的 HTTP 錯誤
+func requireRoomModerator(e echo.Context, room string) error {
+	if middlewares.HasRole(e, config.RoleAdmin, config.RoleModerator) {
+		return nil
+	}
+	moderator, err := isRoomModerator(room, e.Get("username").(string))
+	if err != nil {
+		config.Logger.Error("Error checking room role:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"error": "Error checking room role"})
+	}
+	if !moderator {
+		return echo.NewHTTPError(http.StatusForbidden, echo.Map{"error": errNotRoomModerator.Error()})
+	}
+	return nil
+}
+
+// requireRoomOwner 確認目前用戶為房間擁有者或管理員，否則回傳 403
+func requireRoomOwner(e echo.Context, room string) error {
+	if middlewares.HasRole(e, config.RoleAdmin) {
+		return nil
+	}
+	owner, err := isRoomOwner(room, e.Get("username").(string))
+	if err != nil {
+		config.Logger.Error("Error checking room role:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"error": "Error checking room role"})
+	}
+	if !owner {
+		return echo.NewHTTPError(http.StatusForbidden, echo.Map{"error": errNotRoomOwner.Error()})
+	}
+	return nil
+}
+
+// GetRoomPins 取得房間置頂訊息
+func GetRoomPins(e echo.Context) error {
+	room := e.Param("room")
+	member, err := isRoomMember(room, e.Get("username").(string))
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error fetching pins"})
+	}
+	if !member {
+		return e.JSON(http.StatusForbidden, echo.Map{"error": "Not a member of this room"})
+	}
+
+	pins, err := listRoomPins(room)
+	if err != nil {
+		config.Logger.Error("Error fetching pins:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error fetching pins"})
+	}
+	return e.JSON(http.StatusOK, echo.Map{"pins": pins})
+}
+
+// PinRoomMessage 置頂訊息
+func PinRoomMessage(e echo.Context) error {
+	room := e.Param("room")
+	if err := requireRoomModerator(e, room); err != nil {
+		return err
+	}
+
+	var request struct {
+		MessageID int64 `json:"messageId"`
+	}
+	if err := e.Bind(&request); err != nil || request.MessageID == 0 {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input"})
+	}
+
+	pin, err := pinMessage(room, request.MessageID, e.Get("username").(string))
+	if errors.Is(err, errMessageNotInRoom) {
+		return e.JSON(http.StatusNotFound, echo.Map{"error": err.Error()})
+	}
+	if err != nil {
+		config.Logger.Error("Error pinning message:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error pinning message"})
+	}
+	return e.JSON(http.StatusOK, pin)
+}
+
+// UnpinRoomMessage 取消置頂訊息
+func UnpinRoomMessage(e echo.Context) error {
+	room := e.Param("room")
+	if err := requireRoomModerator(e, room); err != nil {
+		return err
+	}
+
+	messageID, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid message ID"})
+	}
+
+	err = unpinMessage(room, messageID, e.Get("username").(string))
+	if errors.Is(err, errMessageNotInRoom) {
+		return e.JSON(http.StatusNotFound, echo.Map{"error": "Pin not found"})
+	}
+	if err != nil {
+		config.Logger.Error("Error unpinning message:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error unpinning message"})
+	}
+	return e.JSON(http.StatusOK, echo.Map{"status": "Message unpinned"})
+}
+
+// SetRoomRetention 設定房間訊息的預設保留秒數，0 表示永久保留
+func SetRoomRetention(e echo.Context) error {
+	room := e.Param("room")
+	if err := requireRoomModerator(e, room); err != nil {
+		return err
+	}
+
+	var request struct {
+		RetentionSeconds int64 `json:"retentionSeconds"`
+	}
+	if err := e.Bind(&request); err != nil || request.RetentionSeconds < 0 {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input"})
+	}
+
+	before, err := getRoom(room)
+	if err != nil {
+		config.Logger.Error("Error fetching room:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error updating retention"})
+	}
+	_, err = config.PgConn.Exec(config.Ctx, `
+		INSERT INTO rooms (name, retention_seconds) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET retention_seconds = EXCLUDED.retention_seconds`, room, request.RetentionSeconds)
+	if err != nil {
+		config.Logger.Error("Error updating retention:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error updating retention"})
+	}
+
+	BroadcastEventToRoom(room, map[string]interface{}{
+		"type":             "roomRetention",
+		"retentionSeconds": request.RetentionSeconds,
+	})
+	audit(e, config.AuditEntry{Action: "room.retention", TargetType: auditTargetRoom, Target: room, Room: room},
+		echo.Map{"retentionSeconds": before.RetentionSeconds}, echo.Map{"retentionSeconds": request.RetentionSeconds})
+	return e.JSON(http.StatusOK, echo.Map{"room": room, "retentionSeconds": request.RetentionSeconds})
+}
+
+// GetRoomAnnouncement 取得房間公告
+func GetRoomAnnouncement(e echo.Context) error {
+	room, err := getRoom(e.Param("room"))
+	if err != nil {
+		config.Logger.Error("Error fetching room:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error fetching announcement"})
+	}
+	return e.JSON(http.StatusOK, room)
+}
+
+// SetRoomAnnouncement 更新房間公告
+func SetRoomAnnouncement(e echo.Context) error {
+	room := e.Param("room")
+	if err := requireRoomModerator(e, room); err != nil {
+		return err
+	}
+
+	var request struct {
+		Announcement string `json:"announcement"`
+	}
+	if err := e.Bind(&request); err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input"})
+	}
+
+	result, err := setAnnouncement(room, request.Announcement, e.Get("username").(string))
+	if err != nil {
+		config.Logger.Error("Error updating announcement:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error updating announcement"})
+	}
+	return e.JSON(http.StatusOK, result)
+}
+
+// GetRoomFilterPolicy 取得房間的過濾設定，僅房間版主可查看
+func GetRoomFilterPolicy(e echo.Context) error {
+	room := e.Param("room")
+	if err := requireRoomModerator(e, room); err != nil {
+		return err
+	}
+
+	roomInfo, err := getRoom(room)
+	if err != nil {
+		config.Logger.Error("Error fetching room:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error fetching filter policy"})
+	}
+	return e.JSON(http.StatusOK, roomInfo.FilterPolicy)
+}
+
+// SetRoomFilterPolicy 更新房間的過濾設定，僅房間擁有者可操作
+func SetRoomFilterPolicy(e echo.Context) error {
+	room := e.Param("room")
+	if err := requireRoomOwner(e, room); err != nil {
+		return err
+	}
+
+	var policy filter.Policy
+	if err := e.Bind(&policy); err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input"})
+	}
+	if err := policy.Validate(); err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	before, err := getRoom(room)
+	if err != nil {
+		config.Logger.Error("Error fetching room:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error updating filter policy"})
+	}
+	_, err = config.PgConn.Exec(config.Ctx, `
+		INSERT INTO rooms (name, filter_policy) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET filter_policy = EXCLUDED.filter_policy`, room, policy)
+	if err != nil {
+		config.Logger.Error("Error updating filter policy:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error updating filter policy"})
+	}
+	config.InvalidateRoomDictionary(room)
+	audit(e, config.AuditEntry{Action: "room.filter_policy", TargetType: auditTargetRoom, Target: room, Room: room}, before.FilterPolicy, policy)
+	return e.JSON(http.StatusOK, policy)
+}
+
+// SetRoomOwner 由管理員指定房間擁有者，body 為 {"username": "alice"}
+func SetRoomOwner(e echo.Context) error {
+	room := e.Param("room")
+	var request struct {
+		Username string `json:"username"`
+	}
+	if err := e.Bind(&request); err != nil || strings.TrimSpace(request.Username) == "" {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input"})
+	}
+	username := strings.TrimSpace(request.Username)
+
+	if _, err := config.UserRoles(username); errors.Is(err, config.ErrUserNotFound) {
+		return e.JSON(http.StatusNotFound, echo.Map{"error": "User not found"})
+	} else if err != nil {
+		config.Logger.Error("Error fetching user:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error setting room owner"})
+	}
+
+	if err := setRoomOwner(room, username); err != nil {
+		config.Logger.Error("Error setting room owner:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error setting room owner"})
+	}
+	audit(e, config.AuditEntry{Action: "room.owner", TargetType: auditTargetRoom, Target: room, Room: room}, nil, map[string]interface{}{"owner": username})
+	return e.JSON(http.StatusOK, echo.Map{"room": room, "owner": username})
+}
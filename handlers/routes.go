@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"example.com/m/config"
 	"example.com/m/middlewares"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -15,11 +16,14 @@ func SetupRoutes(e *echo.Echo) {
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 
+	// 為每個請求產生 X-Request-ID，寫入稽核紀錄以便追查
+	e.Use(middleware.RequestID())
+
 	// 路由中間件，計算請求數量和延遲
 	e.Use(middlewares.RequestMetricsMiddleware)
 
-	// 路由设置
-	e.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
+	// 路由设置，监控指标仅限 Prometheus 的抓取凭证或管理员与机器人帐号读取
+	e.GET("/metrics", echo.WrapHandler(promhttp.Handler()), middlewares.ScrapeTokenOrRole(config.MetricsToken, config.RoleAdmin, config.RoleBot))
 
 	e.POST("/register", RegisterUser)
 	e.POST("/login", LoginUser)
@@ -29,10 +33,64 @@ func SetupRoutes(e *echo.Echo) {
 
 	// 使用 JWT 中间件保护以下路由
 	protected := e.Group("/api")
-	protected.Use(middlewares.MiddlewareJWT)
+	protected.Use(middlewares.MiddlewareJWT, RejectBanned)
 	protected.GET("/online-users", GetOnlineUsers)
 	protected.GET("/chat-history", GetChatHistory)
 	protected.GET("/latest-chat-date", GetLatestChatDate)
+	protected.POST("/uploads", UploadAttachment)
+	protected.GET("/uploads/:id", GetAttachment)
+	protected.GET("/uploads/:id/thumbnail", GetAttachmentThumbnail)
+	protected.GET("/rooms/:room/pins", GetRoomPins)
+	protected.POST("/rooms/:room/pins", PinRoomMessage)
+	protected.DELETE("/rooms/:room/pins/:id", UnpinRoomMessage)
+	protected.GET("/rooms/:room/announcement", GetRoomAnnouncement)
+	protected.PUT("/rooms/:room/announcement", SetRoomAnnouncement)
+	protected.PUT("/rooms/:room/retention", SetRoomRetention)
+	protected.GET("/rooms/:room/filter-policy", GetRoomFilterPolicy)
+	protected.PUT("/rooms/:room/filter-policy", SetRoomFilterPolicy)
+	protected.GET("/rooms/:room/sanctions", GetRoomSanctions)
+	protected.POST("/rooms/:room/mutes", MuteRoomUser)
+	protected.DELETE("/rooms/:room/mutes/:username", UnmuteRoomUser)
+	protected.POST("/rooms/:room/kicks", KickRoomUser)
+	protected.DELETE("/rooms/:room/kicks/:username", UnkickRoomUser)
+	protected.POST("/scheduled-messages", CreateScheduledMessage)
+	protected.GET("/scheduled-messages", ListScheduledMessages)
+	protected.DELETE("/scheduled-messages/:id", CancelScheduledMessage)
+	protected.POST("/messages/:id/reminders", CreateReminder)
+	protected.GET("/reminders", ListReminders)
+	protected.DELETE("/reminders/:id", CancelReminder)
+	protected.POST("/messages/:id/report", ReportMessage)
+	protected.GET("/moderation/queue", ListModerationQueue)
+	protected.GET("/moderation/queue/:id", GetModerationItem)
+	protected.POST("/moderation/queue/:id/approve", ApproveModerationItem)
+	protected.POST("/moderation/queue/:id/reject", RejectModerationItem)
+
+	// 管理員路由，詞庫與角色僅限管理員；全站處分與稽核紀錄開放給全域版主
+	admin := protected.Group("/admin", middlewares.RequireRole(config.RoleAdmin))
+	staff := protected.Group("/admin", middlewares.RequireRole(config.RoleAdmin, config.RoleModerator))
+	admin.GET("/sensitive-words", ListSensitiveWords)
+	admin.POST("/sensitive-words", AddSensitiveWords)
+	admin.PUT("/sensitive-words/:word", UpdateSensitiveWord)
+	admin.DELETE("/sensitive-words/:word", RemoveSensitiveWord)
+	admin.POST("/sensitive-words/import", ImportSensitiveWords)
+	admin.POST("/sensitive-words/upload", UploadSensitiveWords)
+	admin.POST("/sensitive-words/reload", ReloadSensitiveWords)
+	admin.POST("/sensitive-words/sync", SyncSensitiveWordsFile)
+	admin.POST("/sensitive-words/test", TestSensitiveWords)
+	admin.GET("/allowlist", ListAllowTerms)
+	admin.POST("/allowlist", AddAllowTerms)
+	admin.DELETE("/allowlist/:term", RemoveAllowTerm)
+	admin.GET("/users/:username/roles", GetUserRoles)
+	admin.POST("/users/:username/roles", GrantUserRole)
+	admin.DELETE("/users/:username/roles/:role", RevokeUserRole)
+	admin.PUT("/rooms/:room/owner", SetRoomOwner)
+	staff.GET("/sanctions", ListSanctions)
+	staff.POST("/mutes", MuteUser)
+	staff.DELETE("/mutes/:username", UnmuteUser)
+	staff.POST("/kicks", KickUser)
+	staff.POST("/bans", BanUser)
+	staff.DELETE("/bans/:username", UnbanUser)
+	staff.GET("/audit", ListAuditLog)
 
 	// 添加 CORS 支持
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
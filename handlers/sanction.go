@@ -0,0 +1,582 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"example.com/m/config"
+	"example.com/m/middlewares"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// 處分種類
+const (
+	sanctionMute = "mute" // 禁言，可限定房間
+	sanctionKick = "kick" // 踢出：房間內移除成員資格，到期前無法重新加入或發言；全域時關閉用戶目前的連線，只留下紀錄
+	sanctionBan  = "ban"  // 封鎖，無法登入與建立 WebSocket 連線
+)
+
+const (
+	sanctionCacheMiss       = 10 * time.Minute // 沒有處分時的快取時間，新增或解除處分時會直接更新快取
+	defaultRoomKickDuration = 10 * time.Minute // 未指定時間的房間踢出，到期後才能重新加入
+)
+
+var (
+	errCannotSanction      = errors.New("Room moderators cannot be sanctioned by other moderators")
+	errCannotSanctionSelf  = errors.New("Users cannot sanction themselves")
+	errCannotSanctionStaff = errors.New("Admins cannot be sanctioned and moderators can only be sanctioned by admins")
+	errNoSanction          = errors.New("No active sanction for this user")
+	errNotRoomMember       = errors.New("User is not a member of this room")
+)
+
+// sanctionError 用戶被禁言、踢出房間或封鎖，訊息不會發布
+type sanctionError struct {
+	Sanction config.Sanction
+}
+
+func (e *sanctionError) Error() string {
+	if e.Sanction.Kind == sanctionBan {
+		return "user is banned"
+	}
+	if e.Sanction.Kind == sanctionKick {
+		return "user is kicked from this room"
+	}
+	if e.Sanction.Room != "" {
+		return "user is muted in this room"
+	}
+	return "user is muted"
+}
+
+// frame 通知用戶的 WebSocket 消息
+func (e *sanctionError) frame() map[string]interface{} {
+	frameType := "muted"
+	switch e.Sanction.Kind {
+	case sanctionBan:
+		frameType = "banned"
+	case sanctionKick:
+		frameType = "kicked"
+	}
+	return map[string]interface{}{
+		"type":      frameType,
+		"room":      e.Sanction.Room,
+		"error":     e.Error(),
+		"reason":    e.Sanction.Reason,
+		"expiresAt": e.Sanction.ExpiresAt,
+	}
+}
+
+func sanctionCacheKey(kind, room, username string) string {
+	return "sanction:" + kind + ":" + room + ":" + username
+}
+
+// cacheSanction 快取處分狀態，有處分時快取到處分結束，沒有處分時快取空字串
+func cacheSanction(kind, room, username string, sanction *config.Sanction) {
+	key := sanctionCacheKey(kind, room, username)
+	value, ttl := "", sanctionCacheMiss
+	if sanction != nil {
+		data, err := json.Marshal(sanction)
+		if err != nil {
+			log.Println("Error encoding sanction:", err)
+			return
+		}
+		value, ttl = string(data), 0
+		if sanction.ExpiresAt != nil {
+			if ttl = time.Until(*sanction.ExpiresAt); ttl <= 0 {
+				value, ttl = "", sanctionCacheMiss
+			}
+		}
+	}
+	if err := config.RedisClient.Set(config.Ctx, key, value, ttl).Err(); err != nil {
+		log.Println("Error caching sanction:", err)
+	}
+}
+
+// activeSanction 查詢用戶目前有效的處分，先讀 Redis 快取，沒有快取時查詢資料庫；沒有處分時回傳 nil
+func activeSanction(kind, room, username string) (*config.Sanction, error) {
+	cached, err := config.RedisClient.Get(config.Ctx, sanctionCacheKey(kind, room, username)).Result()
+	if err == nil {
+		if cached == "" {
+			return nil, nil
+		}
+		var sanction config.Sanction
+		if err := json.Unmarshal([]byte(cached), &sanction); err == nil {
+			if sanction.ExpiresAt == nil || sanction.ExpiresAt.After(time.Now()) {
+				return &sanction, nil
+			}
+			return nil, nil
+		}
+	} else if err != redis.Nil {
+		log.Println("Error reading sanction cache:", err)
+	}
+
+	sanction := config.Sanction{Kind: kind, Room: room, Username: username}
+	err = config.PgConn.QueryRow(config.Ctx, `
+		SELECT id, reason, created_by, created_at, expires_at FROM user_sanctions
+		WHERE kind = $1 AND room = $2 AND username = $3 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at DESC LIMIT 1`, kind, room, username).
+		Scan(&sanction.ID, &sanction.Reason, &sanction.CreatedBy, &sanction.CreatedAt, &sanction.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		cacheSanction(kind, room, username, nil)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cacheSanction(kind, room, username, &sanction)
+	return &sanction, nil
+}
+
+// checkMuted 檢查用戶是否在房間內被禁言（全域禁言優先），被禁言時回傳 sanctionError
+func checkMuted(room, username string) error {
+	for _, scope := range []string{"", room} {
+		sanction, err := activeSanction(sanctionMute, scope, username)
+		if err != nil {
+			return err
+		}
+		if sanction != nil {
+			return &sanctionError{Sanction: *sanction}
+		}
+	}
+	return nil
+}
+
+// checkBanned 檢查用戶是否被封鎖，被封鎖時回傳 sanctionError
+func checkBanned(username string) error {
+	sanction, err := activeSanction(sanctionBan, "", username)
+	if err != nil {
+		return err
+	}
+	if sanction != nil {
+		return &sanctionError{Sanction: *sanction}
+	}
+	return nil
+}
+
+// checkKicked 檢查用戶是否被踢出房間且尚未到期，被踢出時回傳 sanctionError
+func checkKicked(room, username string) error {
+	sanction, err := activeSanction(sanctionKick, room, username)
+	if err != nil {
+		return err
+	}
+	if sanction != nil {
+		return &sanctionError{Sanction: *sanction}
+	}
+	return nil
+}
+
+// tracksSanction 處分是否有持續的狀態：全域踢出只關閉當下的連線，其他處分在到期或解除前都有效
+func tracksSanction(kind, room string) bool {
+	return kind != sanctionKick || room != ""
+}
+
+// RejectBanned 拒絕被封鎖用戶的 API 請求，須放在 MiddlewareJWT 之後；
+// 封鎖前簽發的 token 在到期前仍然有效，因此每個請求都要檢查
+func RejectBanned(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(e echo.Context) error {
+		username, _ := e.Get("username").(string)
+		var sanctionErr *sanctionError
+		if err := checkBanned(username); errors.As(err, &sanctionErr) {
+			return e.JSON(http.StatusForbidden, echo.Map{"error": sanctionErr.Error(), "reason": sanctionErr.Sanction.Reason, "expiresAt": sanctionErr.Sanction.ExpiresAt})
+		} else if err != nil {
+			config.Logger.Error("Error checking ban:", err)
+			return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error checking account status"})
+		}
+		return next(e)
+	}
+}
+
+// issueSanction 記錄處分並更新快取，同一用戶在同一範圍內已有的同種處分會被新的處分取代；
+// duration 為 0 表示永久
+func issueSanction(sanction *config.Sanction, duration time.Duration) error {
+	if duration > 0 {
+		expiresAt := time.Now().Add(duration)
+		sanction.ExpiresAt = &expiresAt
+	}
+
+	tx, err := config.PgConn.Begin(config.Ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(config.Ctx)
+
+	if tracksSanction(sanction.Kind, sanction.Room) {
+		_, err = tx.Exec(config.Ctx, `
+			UPDATE user_sanctions SET revoked_at = NOW(), revoked_by = $4
+			WHERE kind = $1 AND room = $2 AND username = $3 AND revoked_at IS NULL`,
+			sanction.Kind, sanction.Room, sanction.Username, sanction.CreatedBy)
+		if err != nil {
+			return err
+		}
+	}
+	err = tx.QueryRow(config.Ctx, `
+		INSERT INTO user_sanctions (kind, username, room, reason, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		sanction.Kind, sanction.Username, sanction.Room, sanction.Reason, sanction.CreatedBy, sanction.ExpiresAt).Scan(&sanction.ID, &sanction.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(config.Ctx); err != nil {
+		return err
+	}
+
+	if tracksSanction(sanction.Kind, sanction.Room) {
+		cacheSanction(sanction.Kind, sanction.Room, sanction.Username, sanction)
+	}
+	return nil
+}
+
+// revokeSanction 解除用戶的禁言、房間踢出或封鎖並清除快取
+func revokeSanction(kind, room, username, revokedBy string) error {
+	tag, err := config.PgConn.Exec(config.Ctx, `
+		UPDATE user_sanctions SET revoked_at = NOW(), revoked_by = $4
+		WHERE kind = $1 AND room = $2 AND username = $3 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`,
+		kind, room, username, revokedBy)
+	if err != nil {
+		return err
+	}
+	if err := config.RedisClient.Del(config.Ctx, sanctionCacheKey(kind, room, username)).Err(); err != nil {
+		log.Println("Error clearing sanction cache:", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errNoSanction
+	}
+	return nil
+}
+
+// auditSanction 記錄處分的稽核紀錄，e 為 nil 時表示系統自動處分
+func auditSanction(e echo.Context, action string, sanction config.Sanction, before, after interface{}) {
+	audit(e, config.AuditEntry{
+		Action:     action,
+		TargetType: auditTargetUser,
+		Target:     sanction.Username,
+		Room:       sanction.Room,
+		Reason:     sanction.Reason,
+	}, before, after)
+}
+
+// listSanctions 列出目前有效的禁言、房間踢出與封鎖，room 為空時列出全域處分
+func listSanctions(room string) ([]config.Sanction, error) {
+	rows, err := config.PgConn.Query(config.Ctx, `
+		SELECT id, kind, username, room, reason, created_by, created_at, expires_at FROM user_sanctions
+		WHERE room = $1 AND (kind IN ('mute', 'ban') OR (kind = 'kick' AND room <> '')) AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at DESC`, room)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sanctions := []config.Sanction{}
+	for rows.Next() {
+		var s config.Sanction
+		if err := rows.Scan(&s.ID, &s.Kind, &s.Username, &s.Room, &s.Reason, &s.CreatedBy, &s.CreatedAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sanctions = append(sanctions, s)
+	}
+	return sanctions, rows.Err()
+}
+
+// disconnectUser 通知並關閉用戶的所有 WebSocket 連線，返回關閉的連線數
+func disconnectUser(username string, payload map[string]interface{}) int {
+	config.Mu.Lock()
+	defer config.Mu.Unlock()
+
+	var closed []*websocket.Conn
+	for client, name := range config.Clients {
+		if name != username {
+			continue
+		}
+		if err := client.WriteJSON(payload); err != nil {
+			log.Println("Error notifying user before disconnect:", err)
+		}
+		client.Close()
+		closed = append(closed, client)
+	}
+	for _, client := range closed {
+		delete(config.Clients, client)
+	}
+	return len(closed)
+}
+
+// userRooms 列出用戶所在的房間
+func userRooms(username string) ([]string, error) {
+	rows, err := config.PgConn.Query(config.Ctx, "SELECT room FROM room_members WHERE username = $1", username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rooms := []string{}
+	for rows.Next() {
+		var room string
+		if err := rows.Scan(&room); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, rows.Err()
+}
+
+// broadcastSanction 廣播處分事件到受影響的房間；全域處分廣播到用戶所在的所有房間
+func broadcastSanction(eventType string, sanction config.Sanction) {
+	rooms := []string{sanction.Room}
+	if sanction.Room == "" {
+		var err error
+		if rooms, err = userRooms(sanction.Username); err != nil {
+			log.Println("Error fetching user rooms:", err)
+			return
+		}
+	}
+
+	for _, room := range rooms {
+		BroadcastEventToRoom(room, map[string]interface{}{
+			"type":      eventType,
+			"username":  sanction.Username,
+			"by":        sanction.CreatedBy,
+			"reason":    sanction.Reason,
+			"global":    sanction.Room == "",
+			"expiresAt": sanction.ExpiresAt,
+		})
+	}
+}
+
+// sanctionRequest 處分 API 的請求內容
+type sanctionRequest struct {
+	Username        string `json:"username"`
+	Reason          string `json:"reason"`
+	DurationSeconds int64  `json:"durationSeconds"` // 0 表示永久
+}
+
+func bindSanctionRequest(e echo.Context) (*sanctionRequest, error) {
+	var request sanctionRequest
+	if err := e.Bind(&request); err != nil {
+		return nil, err
+	}
+	request.Username = strings.TrimSpace(request.Username)
+	if request.Username == "" || request.DurationSeconds < 0 {
+		return nil, errors.New("invalid sanction request")
+	}
+	return &request, nil
+}
+
+// requireSanctionTarget 確認目前用戶為房間版主，且處分對象是房間成員而不是自己或房間版主
+func requireSanctionTarget(e echo.Context, room, username string) error {
+	if err := requireRoomModerator(e, room); err != nil {
+		return err
+	}
+	if username == e.Get("username").(string) {
+		return echo.NewHTTPError(http.StatusForbidden, echo.Map{"error": errCannotSanctionSelf.Error()})
+	}
+	member, err := isRoomMember(room, username)
+	if err != nil {
+		config.Logger.Error("Error checking room membership:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"error": "Error checking room membership"})
+	}
+	if !member {
+		return echo.NewHTTPError(http.StatusNotFound, echo.Map{"error": errNotRoomMember.Error()})
+	}
+	moderator, err := isRoomModerator(room, username)
+	if err != nil {
+		config.Logger.Error("Error checking room role:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"error": "Error checking room role"})
+	}
+	if moderator {
+		return echo.NewHTTPError(http.StatusForbidden, echo.Map{"error": errCannotSanction.Error()})
+	}
+	return nil
+}
+
+// requireGlobalSanctionTarget 確認全域處分的對象不是自己或管理員，全域版主只有管理員能處分
+func requireGlobalSanctionTarget(e echo.Context, username string) error {
+	if username == e.Get("username").(string) {
+		return echo.NewHTTPError(http.StatusForbidden, echo.Map{"error": errCannotSanctionSelf.Error()})
+	}
+	roles, err := config.UserRoles(username)
+	if errors.Is(err, config.ErrUserNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, echo.Map{"error": "User not found"})
+	}
+	if err != nil {
+		config.Logger.Error("Error fetching user roles:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"error": "Error fetching user roles"})
+	}
+	if slices.Contains(roles, config.RoleAdmin) || (slices.Contains(roles, config.RoleModerator) && !middlewares.HasRole(e, config.RoleAdmin)) {
+		return echo.NewHTTPError(http.StatusForbidden, echo.Map{"error": errCannotSanctionStaff.Error()})
+	}
+	return nil
+}
+
+// applySanction 記錄處分並廣播事件；房間踢出移除房間成員資格，到期前無法重新加入，全域踢出與封鎖會關閉用戶的連線
+func applySanction(e echo.Context, kind, room string) error {
+	request, err := bindSanctionRequest(e)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input"})
+	}
+	if room != "" {
+		if err := requireSanctionTarget(e, room, request.Username); err != nil {
+			return err
+		}
+	} else if err := requireGlobalSanctionTarget(e, request.Username); err != nil {
+		return err
+	}
+	if kind == sanctionKick && room != "" {
+		if err := leaveRoom(room, request.Username); err != nil {
+			config.Logger.Error("Error removing room member:", err)
+			return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error issuing sanction"})
+		}
+	}
+
+	sanction := config.Sanction{
+		Kind:      kind,
+		Username:  request.Username,
+		Room:      room,
+		Reason:    strings.TrimSpace(request.Reason),
+		CreatedBy: e.Get("username").(string),
+	}
+	duration := time.Duration(request.DurationSeconds) * time.Second
+	if kind == sanctionKick && room != "" && duration == 0 {
+		duration = defaultRoomKickDuration
+	}
+	if err := issueSanction(&sanction, duration); err != nil {
+		config.Logger.Error("Error issuing sanction:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error issuing sanction"})
+	}
+	auditSanction(e, "sanction."+kind, sanction, nil, sanction)
+
+	switch kind {
+	case sanctionMute:
+		broadcastSanction("userMuted", sanction)
+	case sanctionKick:
+		broadcastSanction("userKicked", sanction)
+		frame := (&sanctionError{Sanction: sanction}).frame()
+		if room == "" {
+			disconnectUser(sanction.Username, frame)
+		} else {
+			// 廣播只送給房間成員，移除成員資格後用戶的連線就不再收到此房間的消息
+			sendToUser(sanction.Username, frame)
+		}
+	case sanctionBan:
+		broadcastSanction("userBanned", sanction)
+		disconnectUser(sanction.Username, (&sanctionError{Sanction: sanction}).frame())
+	}
+	return e.JSON(http.StatusOK, sanction)
+}
+
+// liftSanction 解除禁言、房間踢出或封鎖並廣播事件
+func liftSanction(e echo.Context, kind, room string) error {
+	username := e.Param("username")
+	if room != "" {
+		if err := requireRoomModerator(e, room); err != nil {
+			return err
+		}
+	}
+
+	moderator := e.Get("username").(string)
+	lifted, err := activeSanction(kind, room, username)
+	if err != nil {
+		log.Println("Error reading sanction before revoking:", err)
+	}
+	err = revokeSanction(kind, room, username, moderator)
+	if errors.Is(err, errNoSanction) {
+		return e.JSON(http.StatusNotFound, echo.Map{"error": err.Error()})
+	}
+	if err != nil {
+		config.Logger.Error("Error revoking sanction:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error revoking sanction"})
+	}
+
+	eventType, action := "userUnmuted", "sanction.unmute"
+	switch kind {
+	case sanctionKick:
+		eventType, action = "userUnkicked", "sanction.unkick"
+	case sanctionBan:
+		eventType, action = "userUnbanned", "sanction.unban"
+	}
+	// 解除前的處分作為變更前的狀態；nil 指標需轉成 nil interface，否則會被記錄為 JSON null
+	var before interface{}
+	if lifted != nil {
+		before = lifted
+	}
+	auditSanction(e, action, config.Sanction{Username: username, Room: room}, before, nil)
+	broadcastSanction(eventType, config.Sanction{Kind: kind, Username: username, Room: room, CreatedBy: moderator})
+	return e.JSON(http.StatusOK, echo.Map{"status": "Sanction revoked"})
+}
+
+// GetRoomSanctions 列出房間內目前被禁言與被踢出的用戶
+func GetRoomSanctions(e echo.Context) error {
+	room := e.Param("room")
+	if err := requireRoomModerator(e, room); err != nil {
+		return err
+	}
+
+	sanctions, err := listSanctions(room)
+	if err != nil {
+		config.Logger.Error("Error listing sanctions:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error listing sanctions"})
+	}
+	return e.JSON(http.StatusOK, echo.Map{"sanctions": sanctions})
+}
+
+// MuteRoomUser 在房間內禁言用戶，durationSeconds 為 0 表示永久
+func MuteRoomUser(e echo.Context) error {
+	return applySanction(e, sanctionMute, e.Param("room"))
+}
+
+// UnmuteRoomUser 解除房間內的禁言
+func UnmuteRoomUser(e echo.Context) error {
+	return liftSanction(e, sanctionMute, e.Param("room"))
+}
+
+// KickRoomUser 將用戶踢出房間：移除其成員資格並在房間內廣播，到期前無法重新加入或在房間發言；
+// durationSeconds 為 0 時使用 defaultRoomKickDuration
+func KickRoomUser(e echo.Context) error {
+	return applySanction(e, sanctionKick, e.Param("room"))
+}
+
+// UnkickRoomUser 解除房間踢出，用戶可以重新加入房間
+func UnkickRoomUser(e echo.Context) error {
+	return liftSanction(e, sanctionKick, e.Param("room"))
+}
+
+// ListSanctions 列出目前有效的全域禁言與封鎖
+func ListSanctions(e echo.Context) error {
+	sanctions, err := listSanctions("")
+	if err != nil {
+		config.Logger.Error("Error listing sanctions:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error listing sanctions"})
+	}
+	return e.JSON(http.StatusOK, echo.Map{"sanctions": sanctions})
+}
+
+// MuteUser 全域禁言用戶
+func MuteUser(e echo.Context) error {
+	return applySanction(e, sanctionMute, "")
+}
+
+// UnmuteUser 解除全域禁言
+func UnmuteUser(e echo.Context) error {
+	return liftSanction(e, sanctionMute, "")
+}
+
+// KickUser 關閉用戶的所有連線並廣播到其所在的房間
+func KickUser(e echo.Context) error {
+	return applySanction(e, sanctionKick, "")
+}
+
+// BanUser 封鎖用戶：關閉其連線，之後無法登入或建立 WebSocket 連線
+func BanUser(e echo.Context) error {
+	return applySanction(e, sanctionBan, "")
+}
+
+// UnbanUser 解除封鎖
+func UnbanUser(e echo.Context) error {
+	return liftSanction(e, sanctionBan, "")
+}
@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/m/config"
+	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	schedulerBatchSize = 50
+	jobLockTimeout     = 5 * time.Minute // 執行中的任務超過此時間未完成，視為實例當機並重新排入
+	maxJobAttempts     = 5
+	reminderChannel    = "chat:reminders" // 到期提醒的通知頻道，內容為「任務編號:用戶名稱」
+)
+
+// errReminderQueued 提醒已標記為待送達，狀態不需要再由 finishJob 更新
+var errReminderQueued = errors.New("reminder queued for delivery")
+
+const jobColumns = "id, kind, username, room, content, message_id, run_at, status, created_at"
+
+// StartScheduler 啟動背景 goroutine，定期執行到期的排程訊息與提醒，並訂閱其他實例發布的提醒
+func StartScheduler(interval time.Duration) {
+	go subscribeReminders()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := runDueJobs(); err != nil {
+				log.Println("Error running scheduled jobs:", err)
+			}
+		}
+	}()
+}
+
+// runDueJobs 鎖定並執行到期的任務
+func runDueJobs() error {
+	jobs, err := claimDueJobs()
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		finishJob(job, executeJob(job))
+	}
+	return nil
+}
+
+// claimDueJobs 以 FOR UPDATE SKIP LOCKED 鎖定到期任務，多個實例同時執行時每個任務只會被一個實例取得
+func claimDueJobs() ([]config.ScheduledJob, error) {
+	rows, err := config.PgConn.Query(config.Ctx, `
+		UPDATE scheduled_jobs SET status = 'running', locked_by = $1, locked_at = NOW(), attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM scheduled_jobs
+			WHERE run_at <= NOW() AND (status = 'pending' OR (status = 'running' AND locked_at < NOW() - $2 * INTERVAL '1 second'))
+			ORDER BY run_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+jobColumns,
+		config.InstanceID, int(jobLockTimeout.Seconds()), schedulerBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	return scanJobs(rows)
+}
+
+func scanJobs(rows pgx.Rows) ([]config.ScheduledJob, error) {
+	defer rows.Close()
+
+	jobs := []config.ScheduledJob{}
+	for rows.Next() {
+		var job config.ScheduledJob
+		if err := rows.Scan(&job.ID, &job.Kind, &job.Username, &job.Room, &job.Content, &job.MessageID, &job.RunAt, &job.Status, &job.CreatedAt); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func executeJob(job config.ScheduledJob) error {
+	switch job.Kind {
+	case "message":
+		message := config.ChatMessage{
+			Room:    job.Room,
+			Sender:  job.Username,
+			Content: job.Content,
+			Time:    time.Now(),
+		}
+		_, err := publishMessage(job.Username, message, 0, nil)
+		return err
+	case "reminder":
+		return queueReminder(job)
+	default:
+		return fmt.Errorf("unknown job kind %q", job.Kind)
+	}
+}
+
+// finishJob 更新任務狀態
+func finishJob(job config.ScheduledJob, jobErr error) {
+	var err error
+	var reviewErr reviewError
+	switch {
+	case jobErr == nil:
+		_, err = config.PgConn.Exec(config.Ctx, "UPDATE scheduled_jobs SET status = 'done', locked_by = NULL, last_error = NULL WHERE id = $1", job.ID)
+	case errors.As(jobErr, &reviewErr) && reviewErr.queueID() != 0:
+		// 已放入审核队列，由版主决定是否发布
+		_, err = config.PgConn.Exec(config.Ctx, "UPDATE scheduled_jobs SET status = 'done', locked_by = NULL, last_error = $2 WHERE id = $1", job.ID, jobErr.Error())
+	case reviewErr != nil, errors.As(jobErr, new(*sanctionError)), errors.Is(jobErr, errNotRoomMember):
+		// 被拒绝的内容、被禁言或已不在房间的用户重试也不会通过，直接标记为失败
+		_, err = config.PgConn.Exec(config.Ctx, "UPDATE scheduled_jobs SET status = 'failed', locked_by = NULL, last_error = $2 WHERE id = $1", job.ID, jobErr.Error())
+	case errors.Is(jobErr, errReminderQueued):
+		// 狀態已由 queueReminder 改為 undelivered
+	default:
+		log.Printf("Scheduled job %d failed: %v", job.ID, jobErr)
+		_, err = config.PgConn.Exec(config.Ctx, `
+			UPDATE scheduled_jobs
+			SET status = CASE WHEN attempts >= $2 THEN 'failed' ELSE 'pending' END,
+				run_at = NOW() + attempts * INTERVAL '30 seconds',
+				locked_by = NULL, last_error = $3
+			WHERE id = $1`, job.ID, maxJobAttempts, jobErr.Error())
+	}
+	if err != nil {
+		log.Printf("Error updating scheduled job %d: %v", job.ID, err)
+	}
+}
+
+func reminderEvent(job config.ScheduledJob) map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "reminder",
+		"reminderId": job.ID,
+		"room":       job.Room,
+		"messageId":  job.MessageID,
+		"note":       job.Content,
+		"remindAt":   job.RunAt,
+	}
+}
+
+// queueReminder 將到期的提醒標記為待送達，再透過 Redis 通知所有實例送給用戶的連線；
+// 用戶沒有連在任何實例上時提醒會留在 undelivered 狀態，不會再被排程器取得，等用戶下次連線時送出
+func queueReminder(job config.ScheduledJob) error {
+	if _, err := config.PgConn.Exec(config.Ctx, "UPDATE scheduled_jobs SET status = 'undelivered', locked_by = NULL WHERE id = $1", job.ID); err != nil {
+		return err
+	}
+	if err := config.RedisClient.Publish(config.Ctx, reminderChannel, fmt.Sprintf("%d:%s", job.ID, job.Username)).Err(); err != nil {
+		log.Println("Error publishing reminder:", err)
+	}
+	return errReminderQueued
+}
+
+// subscribeReminders 訂閱到期提醒的通知，只有本實例上有該用戶的連線時才嘗試送出
+func subscribeReminders() {
+	pubsub := config.RedisClient.Subscribe(config.Ctx, reminderChannel)
+	defer pubsub.Close()
+
+	for msg := range pubsub.Channel() {
+		value, username, _ := strings.Cut(msg.Payload, ":")
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			log.Println("Invalid reminder notification:", msg.Payload)
+			continue
+		}
+		if !userConnected(username) {
+			continue
+		}
+		if err := deliverReminder(id); err != nil {
+			log.Printf("Error delivering reminder %d: %v", id, err)
+		}
+	}
+}
+
+// deliverReminder 先將待送達的提醒標記為完成再送出，多個實例或用戶重新連線同時處理時只有一方會取得；
+// 取得後用戶的連線都已關閉時放回 undelivered，等用戶下次連線時送出
+func deliverReminder(id int64) error {
+	rows, err := config.PgConn.Query(config.Ctx, `
+		UPDATE scheduled_jobs SET status = 'done', last_error = NULL
+		WHERE id = $1 AND status = 'undelivered'
+		RETURNING `+jobColumns, id)
+	if err != nil {
+		return err
+	}
+	jobs, err := scanJobs(rows)
+	if err != nil || len(jobs) == 0 {
+		return err // 已由其他實例或用戶重新連線時送出
+	}
+
+	if sendToUser(jobs[0].Username, reminderEvent(jobs[0])) > 0 {
+		return nil
+	}
+	_, err = config.PgConn.Exec(config.Ctx, "UPDATE scheduled_jobs SET status = 'undelivered' WHERE id = $1 AND status = 'done'", id)
+	return err
+}
+
+// deliverPendingReminders 用戶連線時送出離線期間到期的提醒
+func deliverPendingReminders(conn *websocket.Conn, username string) error {
+	rows, err := config.PgConn.Query(config.Ctx, `
+		UPDATE scheduled_jobs SET status = 'done', last_error = NULL
+		WHERE kind = 'reminder' AND username = $1 AND status = 'undelivered'
+		RETURNING `+jobColumns, username)
+	if err != nil {
+		return err
+	}
+	jobs, err := scanJobs(rows)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		sendToClient(conn, reminderEvent(job))
+	}
+	return nil
+}
+
+func createJob(job *config.ScheduledJob) error {
+	return config.PgConn.QueryRow(config.Ctx, `
+		INSERT INTO scheduled_jobs (kind, username, room, content, message_id, run_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, status, created_at`,
+		job.Kind, job.Username, job.Room, job.Content, job.MessageID, job.RunAt).Scan(&job.ID, &job.Status, &job.CreatedAt)
+}
+
+func listJobs(kind, username string) ([]config.ScheduledJob, error) {
+	rows, err := config.PgConn.Query(config.Ctx, `
+		SELECT `+jobColumns+`
+		FROM scheduled_jobs
+		WHERE kind = $1 AND username = $2 AND status IN ('pending', 'running', 'undelivered')
+		ORDER BY run_at`, kind, username)
+	if err != nil {
+		return nil, err
+	}
+	return scanJobs(rows)
+}
+
+// cancelJob 取消用戶自己尚未執行的任務
+func cancelJob(e echo.Context, kind string) error {
+	id, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid ID"})
+	}
+
+	tag, err := config.PgConn.Exec(config.Ctx, "UPDATE scheduled_jobs SET status = 'cancelled' WHERE id = $1 AND kind = $2 AND username = $3 AND status IN ('pending', 'undelivered')",
+		id, kind, e.Get("username").(string))
+	if err != nil {
+		config.Logger.Error("Error cancelling scheduled job:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error cancelling"})
+	}
+	if tag.RowsAffected() == 0 {
+		return e.JSON(http.StatusNotFound, echo.Map{"error": "Not found"})
+	}
+	return e.JSON(http.StatusOK, echo.Map{"status": "Cancelled"})
+}
+
+// CreateScheduledMessage 排程一則訊息在 sendAt 時發送到房間
+func CreateScheduledMessage(e echo.Context) error {
+	var request struct {
+		Room    string    `json:"room"`
+		Content string    `json:"content"`
+		SendAt  time.Time `json:"send_at"`
+	}
+	if err := e.Bind(&request); err != nil || request.Room == "" || strings.TrimSpace(request.Content) == "" {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input"})
+	}
+	if !request.SendAt.After(time.Now()) {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "send_at must be in the future"})
+	}
+
+	job := config.ScheduledJob{
+		Kind:     "message",
+		Username: e.Get("username").(string),
+		Room:     request.Room,
+		Content:  request.Content,
+		RunAt:    request.SendAt,
+	}
+	if err := createJob(&job); err != nil {
+		config.Logger.Error("Error scheduling message:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error scheduling message"})
+	}
+	return e.JSON(http.StatusCreated, job)
+}
+
+// ListScheduledMessages 列出用戶尚未發送的排程訊息
+func ListScheduledMessages(e echo.Context) error {
+	jobs, err := listJobs("message", e.Get("username").(string))
+	if err != nil {
+		config.Logger.Error("Error fetching scheduled messages:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error fetching scheduled messages"})
+	}
+	return e.JSON(http.StatusOK, echo.Map{"scheduledMessages": jobs})
+}
+
+// CancelScheduledMessage 取消排程訊息
+func CancelScheduledMessage(e echo.Context) error {
+	return cancelJob(e, "message")
+}
+
+// CreateReminder 為訊息設定個人提醒，時間到時透過 WebSocket 送出 reminder 事件
+func CreateReminder(e echo.Context) error {
+	username := e.Get("username").(string)
+	messageID, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid message ID"})
+	}
+
+	var request struct {
+		RemindAt time.Time `json:"remind_at"`
+		Note     string    `json:"note"`
+	}
+	if err := e.Bind(&request); err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input"})
+	}
+	if !request.RemindAt.After(time.Now()) {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "remind_at must be in the future"})
+	}
+
+	var room string
+	err = config.PgConn.QueryRow(config.Ctx, "SELECT room FROM chat_messages WHERE id = $1 AND "+visibleMessage, messageID).Scan(&room)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return e.JSON(http.StatusNotFound, echo.Map{"error": "Message not found"})
+	}
+	if err != nil {
+		config.Logger.Error("Error fetching message:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error creating reminder"})
+	}
+
+	member, err := isRoomMember(room, username)
+	if err != nil {
+		config.Logger.Error("Error checking room membership:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error creating reminder"})
+	}
+	if !member {
+		return e.JSON(http.StatusForbidden, echo.Map{"error": "Not a member of this room"})
+	}
+
+	job := config.ScheduledJob{
+		Kind:      "reminder",
+		Username:  username,
+		Room:      room,
+		Content:   request.Note,
+		MessageID: &messageID,
+		RunAt:     request.RemindAt,
+	}
+	if err := createJob(&job); err != nil {
+		config.Logger.Error("Error creating reminder:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error creating reminder"})
+	}
+	return e.JSON(http.StatusCreated, job)
+}
+
+// ListReminders 列出用戶尚未觸發的提醒
+func ListReminders(e echo.Context) error {
+	jobs, err := listJobs("reminder", e.Get("username").(string))
+	if err != nil {
+		config.Logger.Error("Error fetching reminders:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error fetching reminders"})
+	}
+	return e.JSON(http.StatusOK, echo.Map{"reminders": jobs})
+}
+
+// CancelReminder 取消提醒
+func CancelReminder(e echo.Context) error {
+	return cancelJob(e, "reminder")
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"example.com/m/config"
+	"example.com/m/filter"
+	"example.com/m/metrics"
+	"example.com/m/spam"
+	"github.com/jackc/pgx/v5"
+)
+
+// spamError 消息被垃圾消息评分拒绝或暂缓发布
+type spamError struct {
+	Result  spam.Result
+	QueueID int64 // 暂缓发布时审核队列中的编号
+}
+
+func (e *spamError) Error() string {
+	return fmt.Sprintf("message %s by spam detection (score %g)", actionPastTense(e.Result.Action), e.Result.Score)
+}
+
+func (e *spamError) queueID() int64 { return e.QueueID }
+
+// frame 通知发送者的 WebSocket 消息，与敏感词过滤使用相同的消息类型
+func (e *spamError) frame(room, msgTime string) map[string]interface{} {
+	frameType := "messageRejected"
+	if e.Result.Action == filter.ActionHold {
+		frameType = "messageHeld"
+	}
+	frame := map[string]interface{}{
+		"type":  frameType,
+		"room":  room,
+		"time":  msgTime,
+		"error": e.Error(),
+		"rules": e.Result.Rules(),
+		"score": e.Result.Score,
+	}
+	if e.QueueID != 0 {
+		frame["queueId"] = e.QueueID
+	}
+	return frame
+}
+
+// scoreSpam 以垃圾消息规则评分并记录各规则的指标；规则发生错误时只略过该规则
+func scoreSpam(username string, message config.ChatMessage) spam.Result {
+	age, err := accountAge(username)
+	if err != nil {
+		log.Println("Error fetching account age:", err)
+	}
+
+	result, err := config.ScoreSpam(spam.Message{
+		Room:       message.Room,
+		Sender:     username,
+		Content:    message.Content,
+		AccountAge: age,
+	})
+	if err != nil {
+		log.Println("Error scoring spam:", err)
+	}
+
+	for _, signal := range result.Signals {
+		metrics.SpamRuleCounter.WithLabelValues(signal.Rule).Inc()
+		metrics.SpamRuleScore.WithLabelValues(signal.Rule).Add(signal.Score)
+	}
+	metrics.SpamVerdictCounter.WithLabelValues(result.Action).Inc()
+	return result
+}
+
+// accountAge 返回用户注册至今的时间，用户不存在或没有注册时间时返回 0
+func accountAge(username string) (time.Duration, error) {
+	var created *time.Time
+	err := config.PgConn.QueryRow(config.Ctx, "SELECT time FROM users WHERE username = $1", username).Scan(&created)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil || created == nil {
+		return 0, err
+	}
+	return time.Since(*created), nil
+}
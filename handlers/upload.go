@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"example.com/m/config"
+	"example.com/m/storage"
+	"example.com/m/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// UploadAttachment 上傳附件，成功後回傳附件資訊，訊息可透過 attachments 欄位引用其 ID
+func UploadAttachment(e echo.Context) error {
+	username := e.Get("username").(string)
+	room := e.FormValue("room")
+	if room == "" {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Room is required"})
+	}
+
+	// 只有房間成員可以上傳，上傳不會加入房間
+	member, err := isRoomMember(room, username)
+	if err != nil {
+		config.Logger.Error("Error checking room membership:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error saving attachment"})
+	}
+	if !member {
+		return e.JSON(http.StatusForbidden, echo.Map{"error": "Not a member of this room"})
+	}
+
+	fileHeader, err := e.FormFile("file")
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "File is required"})
+	}
+	if fileHeader.Size > config.Uploads.MaxBytes {
+		return e.JSON(http.StatusRequestEntityTooLarge, echo.Map{"error": "File too large"})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Could not read file"})
+	}
+	defer file.Close()
+
+	// 依檔案內容判斷 MIME 類型，不信任客戶端提供的 Content-Type
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return e.JSON(http.StatusBadRequest, echo.Map{"error": "Could not read file"})
+	}
+	head = head[:n]
+	mimeType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !config.Uploads.IsAllowedType(mimeType) {
+		return e.JSON(http.StatusUnsupportedMediaType, echo.Map{"error": "File type not allowed"})
+	}
+
+	storageKey, err := newStorageKey()
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error saving attachment"})
+	}
+
+	// 寫入存放的同時計算 checksum，並以 MaxBytes 限制實際讀取量
+	hasher := sha256.New()
+	reader := io.LimitReader(io.MultiReader(bytes.NewReader(head), file), config.Uploads.MaxBytes+1)
+	size, err := config.FileStorage.Save(config.Ctx, storageKey, io.TeeReader(reader, hasher))
+	if err != nil {
+		config.Logger.Error("Error saving attachment:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error saving attachment"})
+	}
+	if size > config.Uploads.MaxBytes {
+		config.FileStorage.Delete(config.Ctx, storageKey)
+		return e.JSON(http.StatusRequestEntityTooLarge, echo.Map{"error": "File too large"})
+	}
+
+	attachment := config.Attachment{
+		Room:       room,
+		Uploader:   username,
+		FileName:   filepath.Base(fileHeader.Filename),
+		MimeType:   mimeType,
+		Size:       size,
+		Checksum:   hex.EncodeToString(hasher.Sum(nil)),
+		StorageKey: storageKey,
+	}
+
+	if strings.HasPrefix(mimeType, "image/") {
+		attachment.ThumbnailKey = createThumbnail(storageKey)
+		attachment.HasThumbnail = attachment.ThumbnailKey != ""
+	}
+
+	err = config.PgConn.QueryRow(config.Ctx, `
+		INSERT INTO attachments (room, uploader, file_name, mime_type, size, checksum, storage_key, thumbnail_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+		RETURNING id, time`,
+		attachment.Room, attachment.Uploader, attachment.FileName, attachment.MimeType, attachment.Size,
+		attachment.Checksum, attachment.StorageKey, attachment.ThumbnailKey).Scan(&attachment.ID, &attachment.Time)
+	if err != nil {
+		config.Logger.Error("Error saving attachment metadata:", err)
+		config.FileStorage.Delete(config.Ctx, storageKey)
+		if attachment.ThumbnailKey != "" {
+			config.FileStorage.Delete(config.Ctx, attachment.ThumbnailKey)
+		}
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error saving attachment"})
+	}
+
+	return e.JSON(http.StatusCreated, attachment)
+}
+
+// GetAttachment 下載附件，只有房間成員可以存取
+func GetAttachment(e echo.Context) error {
+	attachment, status, err := authorizeAttachment(e)
+	if err != nil {
+		return e.JSON(status, echo.Map{"error": err.Error()})
+	}
+
+	e.Response().Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	return streamObject(e, attachment.StorageKey, attachment.MimeType)
+}
+
+// GetAttachmentThumbnail 取得圖片附件的縮圖
+func GetAttachmentThumbnail(e echo.Context) error {
+	attachment, status, err := authorizeAttachment(e)
+	if err != nil {
+		return e.JSON(status, echo.Map{"error": err.Error()})
+	}
+	if attachment.ThumbnailKey == "" {
+		return e.JSON(http.StatusNotFound, echo.Map{"error": "Thumbnail not available"})
+	}
+
+	return streamObject(e, attachment.ThumbnailKey, "image/jpeg")
+}
+
+// authorizeAttachment 讀取附件資訊並確認目前用戶為房間成員
+func authorizeAttachment(e echo.Context) (*config.Attachment, int, error) {
+	username := e.Get("username").(string)
+	id, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.New("Invalid attachment ID")
+	}
+
+	attachment, err := getAttachment(id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, http.StatusNotFound, errors.New("Attachment not found")
+	}
+	if err != nil {
+		config.Logger.Error("Error fetching attachment:", err)
+		return nil, http.StatusInternalServerError, errors.New("Error fetching attachment")
+	}
+
+	member, err := isRoomMember(attachment.Room, username)
+	if err != nil {
+		config.Logger.Error("Error checking room membership:", err)
+		return nil, http.StatusInternalServerError, errors.New("Error fetching attachment")
+	}
+	if !member && attachment.Uploader != username {
+		return nil, http.StatusForbidden, errors.New("Not a member of this room")
+	}
+
+	return attachment, http.StatusOK, nil
+}
+
+func getAttachment(id int64) (*config.Attachment, error) {
+	var attachment config.Attachment
+	var thumbnailKey *string
+	err := config.PgConn.QueryRow(config.Ctx, `
+		SELECT id, room, uploader, file_name, mime_type, size, checksum, storage_key, thumbnail_key, message_id, time
+		FROM attachments WHERE id = $1`, id).Scan(
+		&attachment.ID, &attachment.Room, &attachment.Uploader, &attachment.FileName, &attachment.MimeType,
+		&attachment.Size, &attachment.Checksum, &attachment.StorageKey, &thumbnailKey, &attachment.MessageID, &attachment.Time)
+	if err != nil {
+		return nil, err
+	}
+	if thumbnailKey != nil {
+		attachment.ThumbnailKey = *thumbnailKey
+		attachment.HasThumbnail = true
+	}
+	return &attachment, nil
+}
+
+// linkAttachments 將訊息引用的附件綁定到訊息上，只接受同一房間內由發送者上傳且尚未使用的附件
+func linkAttachments(message *config.ChatMessage, uploader string, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := config.PgConn.Query(config.Ctx, `
+		UPDATE attachments SET message_id = $1
+		WHERE id = ANY($2) AND uploader = $3 AND room = $4 AND message_id IS NULL
+		RETURNING id`, message.ID, ids, uploader, message.Room)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	message.Attachments = nil
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		message.Attachments = append(message.Attachments, id)
+	}
+	return rows.Err()
+}
+
+func streamObject(e echo.Context, key, contentType string) error {
+	reader, err := config.FileStorage.Open(config.Ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return e.JSON(http.StatusNotFound, echo.Map{"error": "Attachment not found"})
+	}
+	if err != nil {
+		config.Logger.Error("Error opening attachment:", err)
+		return e.JSON(http.StatusInternalServerError, echo.Map{"error": "Error fetching attachment"})
+	}
+	defer reader.Close()
+
+	return e.Stream(http.StatusOK, contentType, reader)
+}
+
+// createThumbnail 為圖片產生縮圖，失敗時只記錄日誌並回傳空字串
+func createThumbnail(storageKey string) string {
+	reader, err := config.FileStorage.Open(config.Ctx, storageKey)
+	if err != nil {
+		log.Println("Error opening image for thumbnail:", err)
+		return ""
+	}
+	defer reader.Close()
+
+	thumbnail, err := utils.GenerateThumbnail(reader, config.Uploads.ThumbnailSize, config.Uploads.MaxPixels)
+	if err != nil {
+		log.Println("Error generating thumbnail:", err)
+		return ""
+	}
+
+	thumbnailKey := storageKey + "_thumb"
+	if _, err := config.FileStorage.Save(config.Ctx, thumbnailKey, bytes.NewReader(thumbnail)); err != nil {
+		log.Println("Error saving thumbnail:", err)
+		return ""
+	}
+	return thumbnailKey
+}
+
+// newStorageKey 產生隨機存放 key，以前兩個字元分目錄避免單一目錄檔案過多
+func newStorageKey() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	key := hex.EncodeToString(buf)
+	return fmt.Sprintf("%s/%s", key[:2], key), nil
+}
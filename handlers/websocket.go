@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"time"
 
@@ -12,6 +13,18 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// wsFrame 客户端发送的 WebSocket 消息
+type wsFrame struct {
+	Type        string  `json:"type"`
+	Token       string  `json:"token"`
+	Room        string  `json:"room"`
+	Content     string  `json:"content"`
+	Time        string  `json:"time"`
+	Attachments []int64 `json:"attachments"` // 引用的附件 ID
+	MessageID   int64   `json:"messageId"`   // pin/unpin 操作的目标消息
+	ExpiresIn   int64   `json:"expiresIn"`   // 消息存活秒数，0 表示使用房间默认值
+}
+
 // 处理 WebSocket 连接时更新在线用户状态
 func HandleWebSocket(e echo.Context) error {
 	// 升级 HTTP 连接到 WebSocket
@@ -21,10 +34,11 @@ func HandleWebSocket(e echo.Context) error {
 		return err
 	}
 	defer conn.Close()
+	connectionID := newConnectionID()
 
 	// 等待接收身份验证消息
 	for {
-		var msg map[string]string
+		var msg wsFrame
 		err := conn.ReadJSON(&msg)
 		if err != nil {
 			log.Println("Error reading JSON:", err)
@@ -32,15 +46,24 @@ func HandleWebSocket(e echo.Context) error {
 		}
 
 		// 处理身份验证消息
-		if msg["type"] == "auth" {
-			tokenString := msg["token"]
-			log.Printf("Received token: %s", tokenString)
-
-			claims, err := middlewares.ParseToken(tokenString)
+		if msg.Type == "auth" {
+			claims, err := middlewares.ParseToken(msg.Token)
 
 			if err == nil {
 				username := claims.Username
+
+				// 被封锁的用户不能建立连接
+				var sanctionErr *sanctionError
+				if err := checkBanned(username); errors.As(err, &sanctionErr) {
+					sendToClient(conn, sanctionErr.frame())
+					break
+				} else if err != nil {
+					log.Println("Error checking ban:", err)
+				}
+
+				config.Mu.Lock()
 				config.Clients[conn] = username // 将用户添加到连接列表
+				config.Mu.Unlock()
 				log.Printf("User %s connected", username)
 				BroadcastUserStatus(username, true) // 广播用户上线状态
 
@@ -48,6 +71,11 @@ func HandleWebSocket(e echo.Context) error {
 				if err := utils.UpdateUserOnlineStatus(config.RedisClient, config.Ctx, username, true); err != nil {
 					log.Println("Error updating online status in Redis:", err)
 				}
+
+				// 送出离线期间到期的提醒
+				if err := deliverPendingReminders(conn, username); err != nil {
+					log.Println("Error delivering pending reminders:", err)
+				}
 			} else {
 				log.Println("Could not parse claims")
 				break
@@ -55,38 +83,90 @@ func HandleWebSocket(e echo.Context) error {
 		}
 
 		// 处理聊天消息
-		if msg["type"] == "message" {
-			room := msg["room"]
-			sender := msg["sender"]
-			content := msg["content"]
-			timeStr := msg["time"]
+		if msg.Type == "message" {
+			// 发送者一律使用已验证的用户名，不采用客户端传来的值
+			username := clientUsername(conn)
+			if username == "" {
+				sendError(conn, "Not authenticated")
+				continue
+			}
 
-			msgTime, err := time.Parse(time.RFC3339, timeStr)
+			msgTime, err := time.Parse(time.RFC3339, msg.Time)
 			if err != nil {
 				log.Println("Invalid message time:", err)
 				continue
 			}
 
-			filteredMessage := config.FilterMessage(content) // 使用过滤后的消息内容
+			// 发送频率超过限制时拒绝，屡次超过会被自动禁言
+			var rateErr *rateLimitError
+			if err := checkRateLimit(connectionID, username, msg.Room); errors.As(err, &rateErr) {
+				sendToClient(conn, rateErr.frame(msg.Room))
+				continue
+			}
 
 			message := config.ChatMessage{
-				Room:    room,
-				Sender:  sender,
-				Content: filteredMessage, // 使用过滤后的消息内容
+				Room:    msg.Room,
+				Sender:  username,
+				Content: msg.Content,
 				Time:    msgTime,
 			}
+			if _, err := publishMessage(username, message, msg.ExpiresIn, msg.Attachments); err != nil {
+				var filterErr *filterError
+				if errors.As(err, &filterErr) {
+					sendFilterVerdict(conn, msg, filterErr)
+					continue
+				}
+				var spamErr *spamError
+				if errors.As(err, &spamErr) {
+					sendToClient(conn, spamErr.frame(msg.Room, msg.Time))
+					continue
+				}
+				var hookErr *hookError
+				if errors.As(err, &hookErr) {
+					sendToClient(conn, hookErr.frame(msg.Room, msg.Time))
+					continue
+				}
+				var sanctionErr *sanctionError
+				if errors.As(err, &sanctionErr) {
+					sendToClient(conn, sanctionErr.frame())
+					continue
+				}
+				if errors.Is(err, errNotRoomMember) {
+					sendError(conn, "Join the room before sending messages")
+					continue
+				}
+				log.Println("Error publishing message:", err)
+				continue
+			}
+		}
 
-			if err := saveMessageToDB(message); err != nil {
-				log.Println("Error saving message to DB:", err)
+		// 处理加入房间，加入后立即发送房间公告与置顶消息
+		if msg.Type == "join" {
+			username := clientUsername(conn)
+			if username == "" || msg.Room == "" {
+				sendError(conn, "Not authenticated or missing room")
 				continue
 			}
+			if err := joinRoom(msg.Room, username); err != nil {
+				var sanctionErr *sanctionError
+				if errors.As(err, &sanctionErr) {
+					sendToClient(conn, sanctionErr.frame())
+					continue
+				}
+				log.Println("Error joining room:", err)
+				continue
+			}
+			sendRoomState(conn, msg.Room)
+		}
 
-			BroadcastMessageToRoom(room, message)
+		// 处理置顶与取消置顶
+		if msg.Type == "pin" || msg.Type == "unpin" {
+			handlePinFrame(conn, msg)
 		}
 
 		// 处理登出消息
-		if msg["type"] == "logout" {
-			username := config.Clients[conn]
+		if msg.Type == "logout" {
+			username := clientUsername(conn)
 			log.Printf("User %s logging out", username)
 
 			// 更新用户在线状态到 Redis
@@ -101,8 +181,10 @@ func HandleWebSocket(e echo.Context) error {
 	}
 
 	// 处理用户断开连接
+	config.Mu.Lock()
 	username := config.Clients[conn]
 	delete(config.Clients, conn)
+	config.Mu.Unlock()
 	log.Printf("User %s disconnected", username)
 
 	// 更新用户在线状态到 Redis
@@ -116,15 +198,30 @@ func HandleWebSocket(e echo.Context) error {
 	return nil
 }
 
-// 广播消息到房间
+// 广播消息到房间，只送给房间成员的连接
 func BroadcastMessageToRoom(room string, message config.ChatMessage) {
-	for client, _ := range config.Clients {
+	members, err := roomMembers(room)
+	if err != nil {
+		config.Logger.Error("Error fetching room members:", err)
+		return
+	}
+
+	config.Mu.Lock()
+	defer config.Mu.Unlock()
+
+	for client, username := range config.Clients {
+		if !members[username] {
+			continue
+		}
 		err := client.WriteJSON(map[string]interface{}{
-			"type":    "message",
-			"room":    message.Room,
-			"sender":  message.Sender,
-			"content": message.Content,
-			"time":    message.Time,
+			"type":        "message",
+			"id":          message.ID,
+			"room":        message.Room,
+			"sender":      message.Sender,
+			"content":     message.Content,
+			"time":        message.Time,
+			"attachments": message.Attachments,
+			"expiresAt":   message.ExpiresAt,
 		})
 		if err != nil {
 			config.Logger.Error("Error broadcasting message:", err)
@@ -136,6 +233,158 @@ func BroadcastMessageToRoom(room string, message config.ChatMessage) {
 	}
 }
 
+// clientUsername 返回连接已验证的用户名，未验证时为空字符串
+func clientUsername(conn *websocket.Conn) string {
+	config.Mu.Lock()
+	defer config.Mu.Unlock()
+	return config.Clients[conn]
+}
+
+// userConnected 用户在本实例上是否有已验证的连接
+func userConnected(username string) bool {
+	config.Mu.Lock()
+	defer config.Mu.Unlock()
+
+	for _, name := range config.Clients {
+		if name == username {
+			return true
+		}
+	}
+	return false
+}
+
+// 发送消息给单一客户端
+func sendToClient(conn *websocket.Conn, payload map[string]interface{}) {
+	config.Mu.Lock()
+	defer config.Mu.Unlock()
+
+	if err := conn.WriteJSON(payload); err != nil {
+		log.Println("Error sending message to client:", err)
+	}
+}
+
+// 发送消息给用户的所有连接，返回成功送达的连接数
+func sendToUser(username string, payload map[string]interface{}) int {
+	config.Mu.Lock()
+	defer config.Mu.Unlock()
+
+	delivered := 0
+	for client, name := range config.Clients {
+		if name != username {
+			continue
+		}
+		if err := client.WriteJSON(payload); err != nil {
+			log.Println("Error sending message to user:", err)
+			continue
+		}
+		delivered++
+	}
+	return delivered
+}
+
+// 发送错误消息给客户端
+func sendError(conn *websocket.Conn, message string) {
+	sendToClient(conn, map[string]interface{}{
+		"type":  "error",
+		"error": message,
+	})
+}
+
+// 通知发送者消息被拒绝或暂缓发布，只附上命中的分类，不回传敏感词本身
+func sendFilterVerdict(conn *websocket.Conn, msg wsFrame, filterErr *filterError) {
+	frame := map[string]interface{}{
+		"type":       filterErr.frameType(),
+		"room":       msg.Room,
+		"time":       msg.Time,
+		"error":      filterErr.Error(),
+		"categories": filterErr.Verdict.Categories(),
+		"severity":   filterErr.Verdict.MaxSeverity,
+	}
+	if filterErr.QueueID != 0 {
+		frame["queueId"] = filterErr.QueueID
+	}
+	sendToClient(conn, frame)
+}
+
+// 发送房间公告与置顶消息给刚加入的客户端
+func sendRoomState(conn *websocket.Conn, room string) {
+	roomInfo, err := getRoom(room)
+	if err != nil {
+		log.Println("Error fetching room:", err)
+		return
+	}
+	if roomInfo.Announcement != "" {
+		event := announcementEvent(roomInfo)
+		event["room"] = room
+		sendToClient(conn, event)
+	}
+
+	pins, err := listRoomPins(room)
+	if err != nil {
+		log.Println("Error fetching pins:", err)
+		return
+	}
+	sendToClient(conn, map[string]interface{}{
+		"type": "roomPins",
+		"room": room,
+		"pins": pins,
+	})
+}
+
+// 处理 pin/unpin 消息，仅房间版主可操作
+func handlePinFrame(conn *websocket.Conn, msg wsFrame) {
+	username := clientUsername(conn)
+	if username == "" {
+		sendError(conn, "Not authenticated")
+		return
+	}
+
+	moderator, err := isRoomModerator(msg.Room, username)
+	if err != nil {
+		log.Println("Error checking room role:", err)
+		return
+	}
+	if !moderator {
+		sendError(conn, errNotRoomModerator.Error())
+		return
+	}
+
+	if msg.Type == "pin" {
+		_, err = pinMessage(msg.Room, msg.MessageID, username)
+	} else {
+		err = unpinMessage(msg.Room, msg.MessageID, username)
+	}
+	if errors.Is(err, errMessageNotInRoom) {
+		sendError(conn, err.Error())
+	} else if err != nil {
+		log.Println("Error updating pin:", err)
+	}
+}
+
+// 广播事件到房间，事件中会附上房间名称，只送给房间成员的连接
+func BroadcastEventToRoom(room string, event map[string]interface{}) {
+	event["room"] = room
+	members, err := roomMembers(room)
+	if err != nil {
+		config.Logger.Error("Error fetching room members:", err)
+		return
+	}
+
+	config.Mu.Lock()
+	defer config.Mu.Unlock()
+
+	for client, username := range config.Clients {
+		if !members[username] {
+			continue
+		}
+		if err := client.WriteJSON(event); err != nil {
+			config.Logger.Error("Error broadcasting event:", err)
+			client.Close()
+			delete(config.Clients, client)
+		}
+	}
+}
+
 // 广播用户状态
 func BroadcastUserStatus(username string, online bool) {
 	status := "offline"
@@ -143,6 +392,9 @@ func BroadcastUserStatus(username string, online bool) {
 		status = "online"
 	}
 
+	config.Mu.Lock()
+	defer config.Mu.Unlock()
+
 	// 使用临时切片来保存已关闭的客户端
 	var closedClients []*websocket.Conn
 
@@ -165,12 +417,6 @@ func BroadcastUserStatus(username string, online bool) {
 	}
 }
 
-func saveMessageToDB(message config.ChatMessage) error {
-	_, err := config.PgConn.Exec(config.Ctx, "INSERT INTO chat_messages (room, sender, content, time) VALUES ($1, $2, $3, $4)",
-		message.Room, message.Sender, message.Content, message.Time)
-	return err
-}
-
 func saveUserDisconnectTime(username string) error {
 	// 将用户断开时间记录到 PostgreSQL 中
 	_, err := config.PgConn.Exec(config.Ctx, "UPDATE users SET disconnect_time = $1 WHERE username = $2", time.Now(), username)
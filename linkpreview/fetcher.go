@@ -0,0 +1,201 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrBlockedAddress 表示目標位址屬於被禁止的網段（內網、本機等）
+	ErrBlockedAddress = errors.New("linkpreview: blocked address")
+	// ErrTooManyRedirects 表示重新導向次數超過上限
+	ErrTooManyRedirects = errors.New("linkpreview: too many redirects")
+	// ErrNotHTML 表示回應不是 HTML 頁面
+	ErrNotHTML = errors.New("linkpreview: response is not HTML")
+
+	urlPattern = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+`)
+)
+
+// Preview 連結預覽的資訊，來源為 Open Graph / Twitter Card 標籤
+type Preview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	SiteName    string `json:"siteName,omitempty"`
+}
+
+// Fetcher 抓取網頁並解析預覽資訊，內建 SSRF 防護
+type Fetcher struct {
+	Timeout      time.Duration // 單次抓取的總時間上限
+	MaxBytes     int64         // 讀取回應內容的上限
+	MaxRedirects int           // 重新導向次數上限
+
+	// AllowIP 判斷是否允許連線到指定 IP，預設只允許公網位址
+	AllowIP func(ip net.IP) bool
+
+	once   sync.Once
+	client *http.Client
+}
+
+// NewFetcher 建立使用預設限制的 Fetcher
+func NewFetcher() *Fetcher {
+	return &Fetcher{
+		Timeout:      5 * time.Second,
+		MaxBytes:     512 << 10,
+		MaxRedirects: 3,
+		AllowIP:      IsPublicIP,
+	}
+}
+
+// httpClient 建立在撥號階段檢查實際連線 IP 的 HTTP client，避免 DNS rebinding 繞過檢查
+func (f *Fetcher) httpClient() *http.Client {
+	f.once.Do(f.initClient)
+	return f.client
+}
+
+func (f *Fetcher) initClient() {
+	dialer := &net.Dialer{
+		Timeout: f.Timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !f.AllowIP(ip) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+			}
+			return nil
+		},
+	}
+
+	f.client = &http.Client{
+		Timeout: f.Timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   f.Timeout,
+			ResponseHeaderTimeout: f.Timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > f.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("linkpreview: unsupported redirect scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// Fetch 抓取 rawURL 並解析預覽資訊
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Preview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("linkpreview: unsupported scheme %q", u.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "ChatLinkPreview/1.0")
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := f.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("linkpreview: unexpected status %d", resp.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, ErrNotHTML
+	}
+
+	preview := parseHTML(io.LimitReader(resp.Body, f.MaxBytes))
+	preview.URL = resp.Request.URL.String()
+	preview.Image = resolveURL(resp.Request.URL, preview.Image)
+	return preview, nil
+}
+
+// ExtractURLs 從訊息中取出最多 limit 個不重複的 http(s) 連結
+func ExtractURLs(content string, limit int) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, match := range urlPattern.FindAllString(content, -1) {
+		match = strings.TrimRight(match, ".,;:!?)]}")
+		if seen[match] {
+			continue
+		}
+		seen[match] = true
+		urls = append(urls, match)
+		if len(urls) >= limit {
+			break
+		}
+	}
+	return urls
+}
+
+// IsPublicIP 判斷 IP 是否為可公開存取的位址
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, block := range blockedNetworks {
+		if block.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// blockedNetworks 標準函式庫未涵蓋的保留網段
+var blockedNetworks = func() []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range []string{
+		"0.0.0.0/8",     // 本網路
+		"100.64.0.0/10", // CGNAT
+		"192.0.0.0/24",  // IETF 保留
+		"198.18.0.0/15", // 效能測試
+		"240.0.0.0/4",   // 保留
+		"64:ff9b::/96",  // NAT64
+		"2001:db8::/32", // 文件範例
+	} {
+		_, network, _ := net.ParseCIDR(cidr)
+		networks = append(networks, network)
+	}
+	return networks
+}()
+
+func resolveURL(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
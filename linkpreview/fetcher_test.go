@@ -0,0 +1,133 @@
+package linkpreview_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/m/linkpreview"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPage = `<!DOCTYPE html>
+<html><head>
+<title>Fallback Title</title>
+<meta property="og:title" content="Open Graph Title">
+<meta name="twitter:description" content="Twitter description">
+<meta property="og:image" content="/static/cover.png">
+<meta property="og:site_name" content="Example">
+</head><body><p>content</p></body></html>`
+
+// newTestFetcher 建立允許連線到本機的 Fetcher，僅供 httptest 使用
+func newTestFetcher() *linkpreview.Fetcher {
+	f := linkpreview.NewFetcher()
+	f.AllowIP = func(ip net.IP) bool { return ip.IsLoopback() }
+	return f
+}
+
+func TestFetchParsesOpenGraph(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, testPage)
+	}))
+	defer server.Close()
+
+	preview, err := newTestFetcher().Fetch(context.Background(), server.URL+"/article")
+	assert.NoError(t, err)
+	assert.Equal(t, "Open Graph Title", preview.Title)
+	assert.Equal(t, "Twitter description", preview.Description)
+	assert.Equal(t, server.URL+"/static/cover.png", preview.Image)
+	assert.Equal(t, "Example", preview.SiteName)
+}
+
+func TestFetchBlocksPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, testPage)
+	}))
+	defer server.Close()
+
+	// 預設設定下不允許連線到 127.0.0.1
+	_, err := linkpreview.NewFetcher().Fetch(context.Background(), server.URL)
+	assert.ErrorIs(t, err, linkpreview.ErrBlockedAddress)
+}
+
+func TestFetchLimitsRedirects(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, server.URL+"/loop", http.StatusFound)
+	}))
+	defer server.Close()
+
+	_, err := newTestFetcher().Fetch(context.Background(), server.URL)
+	assert.ErrorIs(t, err, linkpreview.ErrTooManyRedirects)
+}
+
+func TestFetchLimitsResponseSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><head>"+strings.Repeat("<!-- padding -->", 1000))
+		fmt.Fprint(w, `<meta property="og:title" content="Too Late"></head></html>`)
+	}))
+	defer server.Close()
+
+	f := newTestFetcher()
+	f.MaxBytes = 1024
+	preview, err := f.Fetch(context.Background(), server.URL)
+	assert.NoError(t, err)
+	assert.Empty(t, preview.Title)
+}
+
+func TestFetchTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	f := newTestFetcher()
+	f.Timeout = 100 * time.Millisecond
+	_, err := f.Fetch(context.Background(), server.URL)
+	assert.Error(t, err)
+}
+
+func TestFetchRejectsNonHTML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	_, err := newTestFetcher().Fetch(context.Background(), server.URL)
+	assert.ErrorIs(t, err, linkpreview.ErrNotHTML)
+}
+
+func TestExtractURLs(t *testing.T) {
+	urls := linkpreview.ExtractURLs("看看 https://example.com/a, 還有 http://example.org/b). 重複 https://example.com/a", 5)
+	assert.Equal(t, []string{"https://example.com/a", "http://example.org/b"}, urls)
+
+	assert.Len(t, linkpreview.ExtractURLs("https://a.com https://b.com https://c.com", 2), 2)
+	assert.Empty(t, linkpreview.ExtractURLs("no links here", 3))
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := map[string]bool{
+		"8.8.8.8":         true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"::1":             false,
+		"fd00::1":         false,
+		"2606:4700::1111": true,
+	}
+	for ip, want := range tests {
+		assert.Equal(t, want, linkpreview.IsPublicIP(net.ParseIP(ip)), ip)
+	}
+}
@@ -0,0 +1,72 @@
+package linkpreview
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// parseHTML 解析 <head> 中的 Open Graph、Twitter Card 標籤與 <title>
+func parseHTML(r io.Reader) *Preview {
+	preview := &Preview{}
+	meta := make(map[string]string)
+	var title string
+
+	tokenizer := html.NewTokenizer(r)
+	inTitle := false
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			return fillPreview(preview, meta, title)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "title":
+				inTitle = tokenType == html.StartTagToken
+			case "meta":
+				var key, content string
+				for _, attr := range token.Attr {
+					switch attr.Key {
+					case "property", "name":
+						key = strings.ToLower(attr.Val)
+					case "content":
+						content = attr.Val
+					}
+				}
+				if key != "" && meta[key] == "" {
+					meta[key] = strings.TrimSpace(content)
+				}
+			case "body":
+				// 預覽資訊都在 <head>，讀到 <body> 即可停止
+				return fillPreview(preview, meta, title)
+			}
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = strings.TrimSpace(string(tokenizer.Text()))
+			}
+		case html.EndTagToken:
+			if tokenizer.Token().Data == "title" {
+				inTitle = false
+			}
+		}
+	}
+}
+
+func fillPreview(preview *Preview, meta map[string]string, title string) *Preview {
+	preview.Title = firstNonEmpty(meta["og:title"], meta["twitter:title"], title)
+	preview.Description = firstNonEmpty(meta["og:description"], meta["twitter:description"], meta["description"])
+	preview.Image = firstNonEmpty(meta["og:image"], meta["twitter:image"], meta["twitter:image:src"])
+	preview.SiteName = meta["og:site_name"]
+	return preview
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"time"
+
 	"example.com/m/config"
 	"example.com/m/handlers"
+	"example.com/m/middlewares"
 	"github.com/labstack/echo/v4"
 )
 
@@ -10,11 +13,20 @@ func main() {
 	// Initialize configurations, databases, and other services
 	config.Init()
 
+	// Check roles against the database so grants and revocations apply without a new login
+	middlewares.RoleResolver = config.CurrentRoles
+
 	e := echo.New()
 
 	// Setup routes
 	handlers.SetupRoutes(e)
 
+	// Purge self-destructing messages in the background
+	handlers.StartMessageJanitor(time.Minute)
+
+	// Fire scheduled messages and reminders
+	handlers.StartScheduler(10 * time.Second)
+
 	// Start the server
 	e.Logger.Fatal(e.Start(":8080"))
 }
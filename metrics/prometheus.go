@@ -66,6 +66,82 @@ var (
 		[]string{"route"},
 	)
 
+	// 敏感詞過濾結果指標，依處理方式分類
+	FilterVerdictCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "chat_filter_verdicts_total",
+			Help: "Number of filtered chat messages by resulting action",
+		},
+		[]string{"action"},
+	)
+
+	// 審核佇列指標，依結果分類（held、reported、hidden、approved、rejected）
+	ModerationCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "chat_moderation_queue_total",
+			Help: "Number of held, reported and hidden messages and review decisions",
+		},
+		[]string{"decision"},
+	)
+
+	// 被頻率限制拒絕的訊息，依令牌不足的範圍分類（user、room、connection）
+	RateLimitedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "chat_rate_limited_total",
+			Help: "Number of chat messages refused by the rate limiter",
+		},
+		[]string{"scope"},
+	)
+
+	// 垃圾訊息規則觸發次數，依規則分類（duplicate、links、caps、repeat、emoji、mentions）
+	SpamRuleCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "chat_spam_rule_hits_total",
+			Help: "Number of chat messages that triggered each spam rule",
+		},
+		[]string{"rule"},
+	)
+
+	// 垃圾訊息規則貢獻的分數，依規則分類
+	SpamRuleScore = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "chat_spam_rule_score_total",
+			Help: "Sum of the spam scores contributed by each rule",
+		},
+		[]string{"rule"},
+	)
+
+	// 垃圾訊息偵測結果，依處理方式分類
+	SpamVerdictCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "chat_spam_verdicts_total",
+			Help: "Number of scored chat messages by resulting action",
+		},
+		[]string{"action"},
+	)
+
+	// 外部審核服務的結果，依呼叫時機（sync、async）與處理方式分類
+	ModerationHookCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "chat_moderation_hook_verdicts_total",
+			Help: "Number of messages checked by the external moderation hook by mode and resulting action",
+		},
+		[]string{"mode", "action"},
+	)
+
+	// 外部審核服務失敗、逾時或返回無效結果的次數
+	ModerationHookErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "chat_moderation_hook_errors_total",
+		Help: "Number of failed, timed out or invalid external moderation hook calls",
+	})
+
+	// 外部審核服務的回應時間
+	ModerationHookDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "chat_moderation_hook_duration_seconds",
+		Help:    "Histogram of external moderation hook call durations in seconds",
+		Buckets: prometheus.DefBuckets,
+	})
+
 	// 響應大小指標
 	ResponseSize = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -88,5 +164,14 @@ func InitMetrics() {
 		prometheus.MustRegister(HttpDuration)
 		prometheus.MustRegister(ActiveUsers)
 		prometheus.MustRegister(ResponseSize)
+		prometheus.MustRegister(FilterVerdictCounter)
+		prometheus.MustRegister(ModerationCounter)
+		prometheus.MustRegister(RateLimitedCounter)
+		prometheus.MustRegister(SpamRuleCounter)
+		prometheus.MustRegister(SpamRuleScore)
+		prometheus.MustRegister(SpamVerdictCounter)
+		prometheus.MustRegister(ModerationHookCounter)
+		prometheus.MustRegister(ModerationHookErrors)
+		prometheus.MustRegister(ModerationHookDuration)
 	})
 }
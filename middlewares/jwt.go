@@ -1,7 +1,9 @@
 package middlewares
 
 import (
+	"crypto/subtle"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -14,14 +16,20 @@ var jwtSecret = []byte("your-secret-key")
 
 // Claims 结构体
 type Claims struct {
-	Username string `json:"username"`
+	Username string   `json:"username"`
+	Roles    []string `json:"roles,omitempty"` // 登入時的全域角色；設定 RoleResolver 後權限檢查改用目前的角色
 	jwt.RegisteredClaims
 }
 
-// 生成 JWT Token
-func GenerateJWT(username string) (string, error) {
+// RoleResolver 读取用户目前的全域角色，设定后 MiddlewareJWT 以此取代 token 中的角色，
+// 角色变更或撤销不需要等 token 过期；为 nil 时使用 token 中的角色
+var RoleResolver func(username string) ([]string, error)
+
+// 生成 JWT Token，roles 为用户的全域角色
+func GenerateJWT(username string, roles ...string) (string, error) {
 	claims := Claims{
 		Username: username,
+		Roles:    roles,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 72)), // 72小时过期
 		},
@@ -69,9 +77,59 @@ func MiddlewareJWT(next echo.HandlerFunc) echo.HandlerFunc {
 			return echo.NewHTTPError(http.StatusUnauthorized, map[string]string{"error": "Invalid token"})
 		}
 
+		roles := claims.Roles
+		if RoleResolver != nil {
+			if roles, err = RoleResolver(claims.Username); err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, map[string]string{"error": "Error checking roles"})
+			}
+		}
+
 		// 将用户信息存入上下文
 		c.Set("username", claims.Username)
+		c.Set("roles", roles)
 
 		return next(c)
 	}
 }
+
+// HasRole 检查 MiddlewareJWT 存入上下文的角色中是否包含任一指定角色
+func HasRole(c echo.Context, roles ...string) bool {
+	userRoles, _ := c.Get("roles").([]string)
+	for _, role := range roles {
+		if slices.Contains(userRoles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireRole 只允许拥有任一指定角色的用户访问，须放在 MiddlewareJWT 之后
+func RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if _, ok := c.Get("username").(string); !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, map[string]string{"error": "Authorization header is required"})
+			}
+			if !HasRole(c, roles...) {
+				return echo.NewHTTPError(http.StatusForbidden, map[string]string{"error": "Insufficient role"})
+			}
+			return next(c)
+		}
+	}
+}
+
+// ScrapeTokenOrRole 供 Prometheus 等监控抓取使用：Bearer 与 token 相同时直接放行，
+// 否则需要拥有任一指定角色的 JWT。token 不随 JWT 过期，需另行轮换；为空时只接受 JWT
+func ScrapeTokenOrRole(token string, roles ...string) echo.MiddlewareFunc {
+	requireRole := RequireRole(roles...)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withJWT := MiddlewareJWT(requireRole(next))
+		return func(c echo.Context) error {
+			header := c.Request().Header.Get("Authorization")
+			if token != "" && subtle.ConstantTimeCompare([]byte(header), []byte("Bearer "+token)) == 1 {
+				return next(c)
+			}
+			return withJWT(c)
+		}
+	}
+}
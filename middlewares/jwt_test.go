@@ -7,7 +7,7 @@ import (
 	"time"
 
 	"example.com/m/middlewares"
-	"github.com/golang-jwt/jwt"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 )
@@ -28,6 +28,15 @@ func TestParseToken_ValidToken(t *testing.T) {
 	assert.Equal(t, "testuser", claims.Username)
 }
 
+func TestParseToken_Roles(t *testing.T) {
+	token, err := middlewares.GenerateJWT("testuser", "admin", "member")
+	assert.NoError(t, err)
+
+	claims, err := middlewares.ParseToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admin", "member"}, claims.Roles)
+}
+
 func TestParseToken_InvalidToken(t *testing.T) {
 	invalidToken := "invalid.token.here"
 	claims, err := middlewares.ParseToken(invalidToken)
@@ -100,3 +109,125 @@ func TestMiddlewareJWT(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, rec.Code)
 	})
 }
+
+func TestRequireRole(t *testing.T) {
+	e := echo.New()
+	e.GET("/admin", func(e echo.Context) error {
+		return e.JSON(http.StatusOK, echo.Map{"status": "success"})
+	}, middlewares.MiddlewareJWT, middlewares.RequireRole("admin", "bot"))
+
+	tests := []struct {
+		name  string
+		roles []string
+		want  int
+	}{
+		{"admin", []string{"member", "admin"}, http.StatusOK},
+		{"bot", []string{"bot"}, http.StatusOK},
+		{"member", []string{"member"}, http.StatusForbidden},
+		{"no roles", nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, _ := middlewares.GenerateJWT("testuser", tt.roles...)
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+
+	t.Run("without MiddlewareJWT", func(t *testing.T) {
+		e := echo.New()
+		e.GET("/admin", func(e echo.Context) error {
+			return e.NoContent(http.StatusOK)
+		}, middlewares.RequireRole("admin"))
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin", nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
+
+func TestMiddlewareJWT_RoleResolver(t *testing.T) {
+	middlewares.RoleResolver = func(username string) ([]string, error) {
+		if username == "revoked" {
+			return []string{"member"}, nil
+		}
+		return []string{"member", "admin"}, nil
+	}
+	t.Cleanup(func() { middlewares.RoleResolver = nil })
+
+	e := echo.New()
+	e.GET("/admin", func(e echo.Context) error {
+		return e.NoContent(http.StatusOK)
+	}, middlewares.MiddlewareJWT, middlewares.RequireRole("admin"))
+
+	tests := []struct {
+		name     string
+		username string
+		roles    []string
+		want     int
+	}{
+		{"revoked role in token", "revoked", []string{"admin"}, http.StatusForbidden},
+		{"granted after login", "promoted", []string{"member"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, _ := middlewares.GenerateJWT(tt.username, tt.roles...)
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestScrapeTokenOrRole(t *testing.T) {
+	e := echo.New()
+	e.GET("/metrics", func(e echo.Context) error {
+		return e.NoContent(http.StatusOK)
+	}, middlewares.ScrapeTokenOrRole("scrape-secret", "admin", "bot"))
+
+	botToken, _ := middlewares.GenerateJWT("prometheus", "bot")
+	memberToken, _ := middlewares.GenerateJWT("alice", "member")
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"scrape token", "Bearer scrape-secret", http.StatusOK},
+		{"wrong scrape token", "Bearer scrape-secre", http.StatusUnauthorized},
+		{"bot JWT", "Bearer " + botToken, http.StatusOK},
+		{"member JWT", "Bearer " + memberToken, http.StatusForbidden},
+		{"missing", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+
+	t.Run("empty token only accepts JWT", func(t *testing.T) {
+		e := echo.New()
+		e.GET("/metrics", func(e echo.Context) error {
+			return e.NoContent(http.StatusOK)
+		}, middlewares.ScrapeTokenOrRole("", "bot"))
+
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Authorization", "Bearer ")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
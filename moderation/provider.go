@@ -0,0 +1,110 @@
+// Package moderation 串接外部的內容審核服務（例如毒性分類器），審核結果使用與敏感詞過濾相同的處理方式
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"example.com/m/filter"
+)
+
+// 呼叫審核服務的時機
+const (
+	ModeSync  = "sync"  // 發布前等待審核結果
+	ModeAsync = "async" // 先發布，事後依審核結果隱藏或標記訊息
+)
+
+// ErrInvalidVerdict 表示審核服務返回了無法使用的結果
+var ErrInvalidVerdict = errors.New("moderation: invalid verdict")
+
+// Request 送交審核的訊息
+type Request struct {
+	Room      string    `json:"room"`
+	Sender    string    `json:"sender"`
+	Content   string    `json:"content"`
+	Time      time.Time `json:"time"`
+	MessageID int64     `json:"messageId,omitempty"` // 非同步審核時為已發布的訊息編號
+}
+
+// Verdict 審核結果，Action 為 filter.ActionAllow、ActionFlag、ActionHold 或 ActionReject
+type Verdict struct {
+	Action     string   `json:"action"`
+	Categories []string `json:"categories,omitempty"` // 服務判定的分類，例如 "toxicity"
+	Score      float64  `json:"score,omitempty"`      // 服務給出的分數，僅供記錄
+	Reason     string   `json:"reason,omitempty"`
+	Failed     bool     `json:"failed,omitempty"` // 服務發生錯誤或逾時，Action 由 Hook.OnError 決定
+}
+
+// Validate 檢查處理方式；外部服務沒有命中位置，無法遮蔽，因此不接受 mask
+func (v Verdict) Validate() error {
+	switch v.Action {
+	case filter.ActionAllow, filter.ActionFlag, filter.ActionHold, filter.ActionReject:
+		return nil
+	}
+	return fmt.Errorf("%w: action %q", ErrInvalidVerdict, v.Action)
+}
+
+// ModerationProvider 內容審核服務
+type ModerationProvider interface {
+	Moderate(ctx context.Context, request Request) (Verdict, error)
+}
+
+// Hook 以逾時與失敗處理方式包裝審核服務
+type Hook struct {
+	Provider ModerationProvider
+	Mode     string        // ModeSync 或 ModeAsync
+	Timeout  time.Duration // 每次呼叫的逾時，0 表示不限制
+	OnError  string        // 服務失敗時的處理方式：ActionAllow（fail-open）、ActionHold 或 ActionReject（fail-closed）
+}
+
+// Async 是否在訊息發布後才審核
+func (h *Hook) Async() bool {
+	return h.Mode == ModeAsync
+}
+
+// Moderate 呼叫審核服務。服務失敗、逾時或返回無效結果時，返回依 OnError 決定的結果與錯誤
+func (h *Hook) Moderate(ctx context.Context, request Request) (Verdict, error) {
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	verdict, err := h.Provider.Moderate(ctx, request)
+	if err == nil {
+		err = verdict.Validate()
+	}
+	if err != nil {
+		return Verdict{Action: h.OnError, Failed: true}, err
+	}
+	return verdict, nil
+}
+
+// ParseMode 讀取呼叫時機，空字串為 ModeSync
+func ParseMode(value string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case "", ModeSync:
+		return ModeSync, nil
+	case ModeAsync:
+		return ModeAsync, nil
+	default:
+		return "", fmt.Errorf("moderation: unknown mode %q", value)
+	}
+}
+
+// ParseFailurePolicy 讀取服務失敗時的處理方式："open"（放行）、"closed"（拒絕）或 "hold"（送交人工審核），空字串為 "open"
+func ParseFailurePolicy(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "open":
+		return filter.ActionAllow, nil
+	case "closed":
+		return filter.ActionReject, nil
+	case "hold":
+		return filter.ActionHold, nil
+	default:
+		return "", fmt.Errorf("moderation: unknown failure policy %q", value)
+	}
+}
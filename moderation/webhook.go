@@ -0,0 +1,69 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxWebhookResponse 讀取審核服務回應的上限
+const maxWebhookResponse = 64 << 10
+
+// WebhookProvider 以 HTTP POST 送出 JSON 格式的 Request，並讀取 JSON 格式的 Verdict：
+//
+//	{"action": "hold", "categories": ["toxicity"], "score": 0.92, "reason": "insult"}
+//
+// 非 2xx 的回應視為錯誤
+type WebhookProvider struct {
+	URL    string
+	Token  string       // 不為空時以 "Authorization: Bearer <Token>" 送出
+	Client *http.Client // 預設為 http.DefaultClient，逾時由 ctx 控制
+}
+
+// NewWebhookProvider 建立 HTTP 審核服務
+func NewWebhookProvider(url, token string) *WebhookProvider {
+	return &WebhookProvider{URL: url, Token: token, Client: http.DefaultClient}
+}
+
+// Moderate 實作 ModerationProvider
+func (w *WebhookProvider) Moderate(ctx context.Context, request Request) (Verdict, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return Verdict{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if w.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.Token)
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Verdict{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxWebhookResponse))
+		return Verdict{}, fmt.Errorf("moderation: webhook returned %s", resp.Status)
+	}
+
+	var verdict Verdict
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxWebhookResponse)).Decode(&verdict); err != nil {
+		return Verdict{}, fmt.Errorf("%w: %v", ErrInvalidVerdict, err)
+	}
+	verdict.Failed = false
+	return verdict, nil
+}
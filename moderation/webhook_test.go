@@ -0,0 +1,131 @@
+package moderation_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/m/filter"
+	"example.com/m/moderation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newClassifier 啟動模擬的毒性分類器，內容為 "you idiot" 時要求送審，並記錄收到的請求
+func newClassifier(t *testing.T) (*httptest.Server, *[]moderation.Request) {
+	var received []moderation.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var request moderation.Request
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received = append(received, request)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch request.Content {
+		case "you idiot":
+			fmt.Fprint(w, `{"action": "hold", "categories": ["toxicity"], "score": 0.93, "reason": "insult"}`)
+		case "mask me":
+			fmt.Fprint(w, `{"action": "mask"}`)
+		default:
+			fmt.Fprint(w, `{"action": "allow", "score": 0.01}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &received
+}
+
+func TestWebhookProvider(t *testing.T) {
+	server, received := newClassifier(t)
+	provider := moderation.NewWebhookProvider(server.URL, "secret")
+	ctx := context.Background()
+	sent := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	verdict, err := provider.Moderate(ctx, moderation.Request{Room: "lobby", Sender: "alice", Content: "you idiot", Time: sent})
+	require.NoError(t, err)
+	assert.Equal(t, moderation.Verdict{Action: filter.ActionHold, Categories: []string{"toxicity"}, Score: 0.93, Reason: "insult"}, verdict)
+	assert.Equal(t, moderation.Request{Room: "lobby", Sender: "alice", Content: "you idiot", Time: sent}, (*received)[0])
+
+	verdict, err = provider.Moderate(ctx, moderation.Request{Content: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, filter.ActionAllow, verdict.Action)
+
+	// 驗證失敗的回應視為錯誤
+	_, err = moderation.NewWebhookProvider(server.URL, "wrong").Moderate(ctx, moderation.Request{Content: "hello"})
+	assert.ErrorContains(t, err, "401")
+}
+
+func TestHookFailurePolicy(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprint(w, `{"action": "allow"}`)
+	}))
+	defer slow.Close()
+	defer close(release)
+	ctx := context.Background()
+
+	tests := []struct {
+		policy string
+		action string
+	}{
+		{"open", filter.ActionAllow},
+		{"closed", filter.ActionReject},
+		{"hold", filter.ActionHold},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			onError, err := moderation.ParseFailurePolicy(tt.policy)
+			require.NoError(t, err)
+			hook := &moderation.Hook{
+				Provider: moderation.NewWebhookProvider(slow.URL, ""),
+				Timeout:  20 * time.Millisecond,
+				OnError:  onError,
+			}
+
+			start := time.Now()
+			verdict, err := hook.Moderate(ctx, moderation.Request{Content: "hello"})
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+			assert.Less(t, time.Since(start), 500*time.Millisecond)
+			assert.Equal(t, moderation.Verdict{Action: tt.action, Failed: true}, verdict)
+		})
+	}
+}
+
+func TestHookRejectsInvalidVerdict(t *testing.T) {
+	server, _ := newClassifier(t)
+	hook := &moderation.Hook{Provider: moderation.NewWebhookProvider(server.URL, "secret"), Timeout: time.Second, OnError: filter.ActionReject}
+
+	// 外部服務不能要求遮蔽
+	verdict, err := hook.Moderate(context.Background(), moderation.Request{Content: "mask me"})
+	assert.ErrorIs(t, err, moderation.ErrInvalidVerdict)
+	assert.Equal(t, filter.ActionReject, verdict.Action)
+	assert.True(t, verdict.Failed)
+
+	verdict, err = hook.Moderate(context.Background(), moderation.Request{Content: "you idiot"})
+	require.NoError(t, err)
+	assert.Equal(t, filter.ActionHold, verdict.Action)
+}
+
+func TestParseSettings(t *testing.T) {
+	mode, err := moderation.ParseMode("")
+	require.NoError(t, err)
+	assert.Equal(t, moderation.ModeSync, mode)
+	mode, err = moderation.ParseMode("ASYNC")
+	require.NoError(t, err)
+	assert.Equal(t, moderation.ModeAsync, mode)
+	_, err = moderation.ParseMode("later")
+	assert.Error(t, err)
+
+	_, err = moderation.ParseFailurePolicy("maybe")
+	assert.Error(t, err)
+}
@@ -0,0 +1,116 @@
+// Package ratelimit 以令牌桶（token bucket）限制操作頻率，提供 Redis 與記憶體兩種實作
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidBucket 表示令牌桶設定格式錯誤
+var ErrInvalidBucket = errors.New("ratelimit: invalid bucket")
+
+// Bucket 令牌桶設定：最多累積 Burst 個令牌，每秒補充 Rate 個，每次操作消耗一個
+type Bucket struct {
+	Burst int     // 桶的容量，即允許的瞬間突發次數，0 表示不限制
+	Rate  float64 // 每秒補充的令牌數
+}
+
+// Enabled 是否啟用此令牌桶
+func (b Bucket) Enabled() bool {
+	return b.Burst > 0 && b.Rate > 0
+}
+
+// String 返回 ParseBucket 可讀取的格式
+func (b Bucket) String() string {
+	return fmt.Sprintf("%d:%s", b.Burst, strconv.FormatFloat(b.Rate, 'f', -1, 64))
+}
+
+// ParseBucket 讀取 "burst:rate" 格式的設定，例如 "10:0.5" 表示最多連續 10 次、每兩秒補充一次；
+// 空字串或 "0" 表示不限制
+func ParseBucket(value string) (Bucket, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "0" {
+		return Bucket{}, nil
+	}
+
+	burst, rate, ok := strings.Cut(value, ":")
+	if !ok {
+		return Bucket{}, fmt.Errorf("%w: %q, expected burst:rate", ErrInvalidBucket, value)
+	}
+	b := Bucket{}
+	var err error
+	if b.Burst, err = strconv.Atoi(strings.TrimSpace(burst)); err != nil || b.Burst < 0 {
+		return Bucket{}, fmt.Errorf("%w: invalid burst %q", ErrInvalidBucket, burst)
+	}
+	if b.Rate, err = strconv.ParseFloat(strings.TrimSpace(rate), 64); err != nil || b.Rate < 0 || math.IsInf(b.Rate, 0) || math.IsNaN(b.Rate) {
+		return Bucket{}, fmt.Errorf("%w: invalid rate %q", ErrInvalidBucket, rate)
+	}
+	return b, nil
+}
+
+// Limit 一個需要檢查的令牌桶
+type Limit struct {
+	Key    string // 令牌桶的識別鍵，例如 "user:alice"
+	Bucket Bucket
+}
+
+// Result Take 的結果
+type Result struct {
+	Allowed    bool
+	RetryAfter time.Duration // 被拒絕時需要等待的時間
+	Key        string        // 被拒絕時，令牌不足的令牌桶
+}
+
+// Limiter 檢查並消耗令牌
+type Limiter interface {
+	// Take 同時檢查所有令牌桶，全部都有令牌時各消耗一個並允許；
+	// 任一不足時不消耗任何令牌，並返回等待最久的令牌桶。未啟用的令牌桶會被略過
+	Take(ctx context.Context, limits ...Limit) (Result, error)
+}
+
+// state 令牌桶在某個時間點的令牌數
+type state struct {
+	tokens float64
+	at     time.Time
+}
+
+// refill 返回補充到 now 時的令牌數，新的令牌桶是滿的
+func refill(s *state, b Bucket, now time.Time) float64 {
+	if s == nil {
+		return float64(b.Burst)
+	}
+	elapsed := now.Sub(s.at).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(b.Burst), s.tokens+elapsed*b.Rate)
+}
+
+// retryAfter 返回累積到一個令牌所需的時間
+func retryAfter(tokens float64, b Bucket) time.Duration {
+	if tokens >= 1 {
+		return 0
+	}
+	return time.Duration(math.Ceil((1 - tokens) / b.Rate * float64(time.Second)))
+}
+
+// ttl 令牌桶從空到滿所需的時間，之後的狀態與新的令牌桶相同，可以刪除
+func (b Bucket) ttl() time.Duration {
+	return time.Duration(math.Ceil(float64(b.Burst) / b.Rate * float64(time.Second)))
+}
+
+// enabled 略過未啟用的令牌桶
+func enabled(limits []Limit) []Limit {
+	result := make([]Limit, 0, len(limits))
+	for _, limit := range limits {
+		if limit.Bucket.Enabled() {
+			result = append(result, limit)
+		}
+	}
+	return result
+}
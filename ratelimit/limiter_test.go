@@ -0,0 +1,115 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"example.com/m/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBucket(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    ratelimit.Bucket
+		wantErr bool
+	}{
+		{"10:0.5", ratelimit.Bucket{Burst: 10, Rate: 0.5}, false},
+		{" 3 : 2 ", ratelimit.Bucket{Burst: 3, Rate: 2}, false},
+		{"", ratelimit.Bucket{}, false},
+		{"0", ratelimit.Bucket{}, false},
+		{"10", ratelimit.Bucket{}, true},
+		{"x:1", ratelimit.Bucket{}, true},
+		{"10:-1", ratelimit.Bucket{}, true},
+		{"10:NaN", ratelimit.Bucket{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ratelimit.ParseBucket(tt.value)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ratelimit.ErrInvalidBucket)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	assert.Equal(t, "10:0.5", ratelimit.Bucket{Burst: 10, Rate: 0.5}.String())
+}
+
+// newLimiter 建立時間可控制的記憶體令牌桶
+func newLimiter() (*ratelimit.MemoryLimiter, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := ratelimit.NewMemoryLimiter()
+	limiter.Now = func() time.Time { return now }
+	return limiter, &now
+}
+
+func TestMemoryLimiterTake(t *testing.T) {
+	limiter, now := newLimiter()
+	ctx := context.Background()
+	user := ratelimit.Limit{Key: "user:alice", Bucket: ratelimit.Bucket{Burst: 3, Rate: 0.5}}
+
+	// 滿的令牌桶允許連續 burst 次
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Take(ctx, user)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed, "take %d", i)
+	}
+
+	result, err := limiter.Take(ctx, user)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, "user:alice", result.Key)
+	assert.Equal(t, 2*time.Second, result.RetryAfter)
+
+	// 補充一秒只有半個令牌
+	*now = now.Add(time.Second)
+	result, _ = limiter.Take(ctx, user)
+	assert.Equal(t, time.Second, result.RetryAfter)
+
+	*now = now.Add(time.Second)
+	result, _ = limiter.Take(ctx, user)
+	assert.True(t, result.Allowed)
+
+	// 長時間未使用後最多補滿 burst
+	*now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		result, _ = limiter.Take(ctx, user)
+		assert.True(t, result.Allowed)
+	}
+	result, _ = limiter.Take(ctx, user)
+	assert.False(t, result.Allowed)
+}
+
+func TestMemoryLimiterTakeAll(t *testing.T) {
+	limiter, _ := newLimiter()
+	ctx := context.Background()
+	user := ratelimit.Limit{Key: "user:alice", Bucket: ratelimit.Bucket{Burst: 5, Rate: 1}}
+	room := ratelimit.Limit{Key: "room:general", Bucket: ratelimit.Bucket{Burst: 1, Rate: 0.1}}
+	disabled := ratelimit.Limit{Key: "connection:1", Bucket: ratelimit.Bucket{}}
+
+	result, err := limiter.Take(ctx, user, room, disabled)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// 房間的令牌不足時整體拒絕，且不消耗用戶的令牌
+	result, _ = limiter.Take(ctx, user, room)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, "room:general", result.Key)
+	assert.Equal(t, 10*time.Second, result.RetryAfter)
+
+	for i := 0; i < 4; i++ {
+		result, _ = limiter.Take(ctx, user)
+		assert.True(t, result.Allowed, "take %d", i)
+	}
+	result, _ = limiter.Take(ctx, user)
+	assert.False(t, result.Allowed)
+
+	// 沒有啟用的令牌桶時一律允許
+	result, _ = limiter.Take(ctx, disabled)
+	assert.True(t, result.Allowed)
+}
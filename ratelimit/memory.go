@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryLimiter 以記憶體保存令牌桶，只在單一實例內有效，適合測試或不需要跨實例共用的限制
+type MemoryLimiter struct {
+	Now func() time.Time // 目前時間，預設為 time.Now
+
+	mu      sync.Mutex
+	buckets map[string]*state
+}
+
+// NewMemoryLimiter 建立記憶體令牌桶
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{Now: time.Now, buckets: make(map[string]*state)}
+}
+
+// Take 實作 Limiter
+func (m *MemoryLimiter) Take(ctx context.Context, limits ...Limit) (Result, error) {
+	limits = enabled(limits)
+	now := m.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tokens := make([]float64, len(limits))
+	result := Result{Allowed: true}
+	for i, limit := range limits {
+		tokens[i] = refill(m.buckets[limit.Key], limit.Bucket, now)
+		if wait := retryAfter(tokens[i], limit.Bucket); wait > result.RetryAfter {
+			result = Result{RetryAfter: wait, Key: limit.Key}
+		}
+	}
+	if !result.Allowed {
+		return result, nil
+	}
+
+	for i, limit := range limits {
+		m.buckets[limit.Key] = &state{tokens: tokens[i] - 1, at: now}
+	}
+	return result, nil
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// takeScript 以 Lua 腳本在 Redis 中原子地檢查並消耗多個令牌桶，演算法與 MemoryLimiter 相同。
+// KEYS 為令牌桶的鍵，ARGV[1] 為目前時間（毫秒），之後每個令牌桶依序為 burst、每毫秒補充的令牌數與存活毫秒數。
+// 返回 {是否允許, 需要等待的毫秒數, 令牌不足的令牌桶序號（從 1 開始）}
+var takeScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local tokens = {}
+local wait, denied = 0, 0
+for i, key in ipairs(KEYS) do
+	local burst = tonumber(ARGV[i * 3 - 1])
+	local rate = tonumber(ARGV[i * 3])
+	local saved = redis.call('HMGET', key, 'tokens', 'at')
+	local t = burst
+	if saved[1] then
+		t = math.min(burst, tonumber(saved[1]) + math.max(0, now - tonumber(saved[2])) * rate)
+	end
+	tokens[i] = t
+	if t < 1 then
+		local w = math.ceil((1 - t) / rate)
+		if w > wait then
+			wait, denied = w, i
+		end
+	end
+end
+if denied > 0 then
+	return {0, wait, denied}
+end
+for i, key in ipairs(KEYS) do
+	redis.call('HSET', key, 'tokens', tostring(tokens[i] - 1), 'at', ARGV[1])
+	redis.call('PEXPIRE', key, ARGV[i * 3 + 1])
+end
+return {1, 0, 0}
+`)
+
+// RedisLimiter 以 Redis 保存令牌桶，多個實例共用同一組限制
+type RedisLimiter struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisLimiter 建立 Redis 令牌桶，prefix 會加在每個令牌桶的鍵前面
+func NewRedisLimiter(client *redis.Client, prefix string) *RedisLimiter {
+	return &RedisLimiter{client: client, prefix: prefix}
+}
+
+// Take 實作 Limiter
+func (r *RedisLimiter) Take(ctx context.Context, limits ...Limit) (Result, error) {
+	limits = enabled(limits)
+	if len(limits) == 0 {
+		return Result{Allowed: true}, nil
+	}
+
+	keys := make([]string, len(limits))
+	args := []interface{}{time.Now().UnixMilli()}
+	for i, limit := range limits {
+		keys[i] = r.prefix + limit.Key
+		args = append(args,
+			limit.Bucket.Burst,
+			strconv.FormatFloat(limit.Bucket.Rate/1000, 'g', -1, 64),
+			limit.Bucket.ttl().Milliseconds()+1)
+	}
+
+	values, err := takeScript.Run(ctx, r.client, keys, args...).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if values[0] == 1 {
+		return Result{Allowed: true}, nil
+	}
+	return Result{
+		RetryAfter: time.Duration(values[1]) * time.Millisecond,
+		Key:        limits[values[2]-1].Key,
+	}, nil
+}
@@ -0,0 +1,135 @@
+package spam
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// History 記錄用戶最近發送過的訊息指紋，供 DuplicateScorer 判斷重複發送
+type History interface {
+	// Record 記錄 sender 在 room 發送了指紋為 fingerprint 的訊息，返回 window 內相同指紋的發送次數與涉及的房間數（皆包含這一次）
+	Record(ctx context.Context, sender, room, fingerprint string, window time.Duration) (count, rooms int, err error)
+}
+
+// DuplicateScorer 重複訊息：Window 內相同內容發送超過 MaxRepeats 次時每多一次加 Weight 分，
+// 發送到多個房間時每多一個房間再加 Weight 分。短於 MinLength 個字元的訊息（例如「好」、「ok」）不列入計算
+type DuplicateScorer struct {
+	History    History
+	Window     time.Duration
+	MaxRepeats int
+	MinLength  int
+	Weight     float64
+}
+
+func (s *DuplicateScorer) Name() string { return RuleDuplicate }
+
+func (s *DuplicateScorer) Score(ctx context.Context, message Message) (float64, string, error) {
+	normalized := normalizeContent(message.Content)
+	if len([]rune(normalized)) < s.MinLength {
+		return 0, "", nil
+	}
+
+	count, rooms, err := s.History.Record(ctx, message.Sender, message.Room, Fingerprint(normalized), s.Window)
+	if err != nil {
+		return 0, "", err
+	}
+	repeats := max(count-s.MaxRepeats, 0) + rooms - 1
+	if repeats <= 0 {
+		return 0, "", nil
+	}
+	return s.Weight * float64(repeats), fmt.Sprintf("sent %d times in %d rooms", count, rooms), nil
+}
+
+// normalizeContent 忽略大小寫與空白的差異
+func normalizeContent(content string) string {
+	return strings.ToLower(strings.Join(strings.Fields(content), " "))
+}
+
+// Fingerprint 返回訊息內容的指紋
+func Fingerprint(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:16])
+}
+
+// MemoryHistory 以記憶體保存訊息指紋，只在單一實例內有效，適合測試
+type MemoryHistory struct {
+	Now func() time.Time // 目前時間，預設為 time.Now
+
+	mu      sync.Mutex
+	entries map[string]*historyEntry
+}
+
+type historyEntry struct {
+	rooms     map[string]int
+	expiresAt time.Time
+}
+
+// NewMemoryHistory 建立記憶體訊息指紋紀錄
+func NewMemoryHistory() *MemoryHistory {
+	return &MemoryHistory{Now: time.Now, entries: make(map[string]*historyEntry)}
+}
+
+// Record 實作 History，與 RedisHistory 相同，紀錄在最後一次發送後 window 內有效
+func (m *MemoryHistory) Record(ctx context.Context, sender, room, fingerprint string, window time.Duration) (int, int, error) {
+	now := m.Now()
+	key := sender + ":" + fingerprint
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.entries[key]
+	if entry == nil || !now.Before(entry.expiresAt) {
+		entry = &historyEntry{rooms: make(map[string]int)}
+		m.entries[key] = entry
+	}
+	entry.rooms[room]++
+	entry.expiresAt = now.Add(window)
+
+	count := 0
+	for _, n := range entry.rooms {
+		count += n
+	}
+	return count, len(entry.rooms), nil
+}
+
+// RedisHistory 以 Redis hash 保存訊息指紋（欄位為房間，值為次數），多個實例共用
+type RedisHistory struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisHistory 建立 Redis 訊息指紋紀錄，prefix 會加在每個鍵前面
+func NewRedisHistory(client *redis.Client, prefix string) *RedisHistory {
+	return &RedisHistory{client: client, prefix: prefix}
+}
+
+// Record 實作 History
+func (r *RedisHistory) Record(ctx context.Context, sender, room, fingerprint string, window time.Duration) (int, int, error) {
+	key := r.prefix + sender + ":" + fingerprint
+
+	pipe := r.client.TxPipeline()
+	pipe.HIncrBy(ctx, key, room, 1)
+	pipe.PExpire(ctx, key, window)
+	values := pipe.HVals(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	count := 0
+	for _, value := range values.Val() {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, err
+		}
+		count += n
+	}
+	return count, len(values.Val()), nil
+}
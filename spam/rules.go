@@ -0,0 +1,172 @@
+package spam
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"example.com/m/linkpreview"
+)
+
+// 規則名稱，也是 Prometheus 指標的 rule 標籤
+const (
+	RuleDuplicate = "duplicate"
+	RuleLinks     = "links"
+	RuleCaps      = "caps"
+	RuleRepeat    = "repeat"
+	RuleEmoji     = "emoji"
+	RuleMentions  = "mentions"
+)
+
+// AllRules 所有內建規則的名稱
+var AllRules = []string{RuleDuplicate, RuleLinks, RuleCaps, RuleRepeat, RuleEmoji, RuleMentions}
+
+func isRule(name string) bool {
+	return slices.Contains(AllRules, name)
+}
+
+// maxCountedLinks 計算連結數量的上限，避免超長訊息花費過多時間
+const maxCountedLinks = 100
+
+// LinkScorer 連結過多：超過 MaxLinks 個連結時每多一個加 Weight 分；
+// 註冊未滿 NewAccountAge 的帳號只允許 NewAccountMaxLinks 個
+type LinkScorer struct {
+	MaxLinks           int
+	NewAccountAge      time.Duration
+	NewAccountMaxLinks int
+	Weight             float64
+}
+
+func (s *LinkScorer) Name() string { return RuleLinks }
+
+func (s *LinkScorer) Score(ctx context.Context, message Message) (float64, string, error) {
+	links := len(linkpreview.ExtractURLs(message.Content, maxCountedLinks))
+	allowed, who := s.MaxLinks, ""
+	if message.AccountAge > 0 && message.AccountAge < s.NewAccountAge {
+		allowed, who = s.NewAccountMaxLinks, " from a new account"
+	}
+	if links <= allowed {
+		return 0, "", nil
+	}
+	return s.Weight * float64(links-allowed), fmt.Sprintf("%d links%s", links, who), nil
+}
+
+// CapsScorer 大寫字母過多：有大小寫之分的字母至少 MinLetters 個且大寫比例超過 MaxRatio 時加 Weight 分。
+// 中文等沒有大小寫的文字不列入計算
+type CapsScorer struct {
+	MinLetters int
+	MaxRatio   float64
+	Weight     float64
+}
+
+func (s *CapsScorer) Name() string { return RuleCaps }
+
+func (s *CapsScorer) Score(ctx context.Context, message Message) (float64, string, error) {
+	var upper, cased int
+	for _, r := range message.Content {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+			cased++
+		case unicode.IsLower(r):
+			cased++
+		}
+	}
+	if cased < s.MinLetters || cased == 0 {
+		return 0, "", nil
+	}
+	ratio := float64(upper) / float64(cased)
+	if ratio <= s.MaxRatio {
+		return 0, "", nil
+	}
+	return s.Weight, fmt.Sprintf("%.0f%% capital letters", ratio*100), nil
+}
+
+// RepeatScorer 重複字元：同一個字元（空白除外）連續出現超過 MaxRun 次時加 Weight 分
+type RepeatScorer struct {
+	MaxRun int
+	Weight float64
+}
+
+func (s *RepeatScorer) Name() string { return RuleRepeat }
+
+func (s *RepeatScorer) Score(ctx context.Context, message Message) (float64, string, error) {
+	var longest, run int
+	var last, repeated rune
+	for _, r := range message.Content {
+		if r == last {
+			run++
+		} else {
+			last, run = r, 1
+		}
+		if run > longest && !unicode.IsSpace(r) {
+			longest, repeated = run, r
+		}
+	}
+	if longest <= s.MaxRun {
+		return 0, "", nil
+	}
+	return s.Weight, fmt.Sprintf("%q repeated %d times", repeated, longest), nil
+}
+
+// EmojiScorer 表情符號過多：超過 MaxEmoji 個時加 Weight 分
+type EmojiScorer struct {
+	MaxEmoji int
+	Weight   float64
+}
+
+func (s *EmojiScorer) Name() string { return RuleEmoji }
+
+func (s *EmojiScorer) Score(ctx context.Context, message Message) (float64, string, error) {
+	count := 0
+	for _, r := range message.Content {
+		if isEmoji(r) {
+			count++
+		}
+	}
+	if count <= s.MaxEmoji {
+		return 0, "", nil
+	}
+	return s.Weight, fmt.Sprintf("%d emoji", count), nil
+}
+
+// isEmoji 判斷是否為表情符號，只計算圖形本身，不計算膚色、變體選擇符等修飾字元
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F1E6 && r <= 0x1F1FF: // 國旗的區域指示符號
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF: // 膚色修飾
+		return false
+	case r >= 0x1F000 && r <= 0x1FAFF:
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // 雜項符號與裝飾符號
+		return true
+	}
+	return false
+}
+
+// mentionPattern 以 @ 開頭的用戶名稱，@ 前面必須是開頭或空白，避免把電子郵件當成提及
+var mentionPattern = regexp.MustCompile(`(?:^|\s)@([\p{L}\p{N}_.-]+)`)
+
+// MentionScorer 大量提及：提及超過 MaxMentions 個不同用戶時每多一個加 Weight 分
+type MentionScorer struct {
+	MaxMentions int
+	Weight      float64
+}
+
+func (s *MentionScorer) Name() string { return RuleMentions }
+
+func (s *MentionScorer) Score(ctx context.Context, message Message) (float64, string, error) {
+	mentioned := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(message.Content, -1) {
+		mentioned[strings.ToLower(match[1])] = true
+	}
+	if len(mentioned) <= s.MaxMentions {
+		return 0, "", nil
+	}
+	return s.Weight * float64(len(mentioned)-s.MaxMentions), fmt.Sprintf("%d users mentioned", len(mentioned)), nil
+}
@@ -0,0 +1,103 @@
+// Package spam 以可插拔的評分規則（SpamScorer）偵測洗版與垃圾訊息，各規則的分數加總後決定處理方式
+package spam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"example.com/m/filter"
+)
+
+// Message 要評分的訊息
+type Message struct {
+	Room       string
+	Sender     string
+	Content    string
+	AccountAge time.Duration // 發送者註冊至今的時間，0 表示未知
+}
+
+// Signal 單一規則的評分結果
+type Signal struct {
+	Rule   string  `json:"rule"`
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"` // 給版主看的說明，例如 "5 links from a new account"
+}
+
+// SpamScorer 評分規則，返回 0 表示未觸發；reason 說明觸發的原因
+type SpamScorer interface {
+	Name() string
+	Score(ctx context.Context, message Message) (score float64, reason string, err error)
+}
+
+// Result Chain.Evaluate 的結果
+type Result struct {
+	Action  string   `json:"action"` // filter.ActionAllow、ActionHold 或 ActionReject
+	Score   float64  `json:"score"`  // 所有規則的分數總和
+	Signals []Signal `json:"signals,omitempty"`
+}
+
+// Rules 返回觸發的規則名稱
+func (r Result) Rules() []string {
+	rules := make([]string, len(r.Signals))
+	for i, signal := range r.Signals {
+		rules[i] = signal.Rule
+	}
+	return rules
+}
+
+// Chain 依序執行評分規則，總分達到 Hold 時送交人工審核，達到 Reject 時拒絕；門檻為 0 表示不使用該處理方式
+type Chain struct {
+	Scorers []SpamScorer
+	Hold    float64
+	Reject  float64
+}
+
+// NewChain 建立評分規則鏈
+func NewChain(hold, reject float64, scorers ...SpamScorer) *Chain {
+	return &Chain{Scorers: scorers, Hold: hold, Reject: reject}
+}
+
+// Evaluate 執行所有規則並加總分數。個別規則發生錯誤時略過該規則，其餘規則的結果仍然有效，錯誤合併後一併返回
+func (c *Chain) Evaluate(ctx context.Context, message Message) (Result, error) {
+	result := Result{Action: filter.ActionAllow}
+	var errs []error
+	for _, scorer := range c.Scorers {
+		score, reason, err := scorer.Score(ctx, message)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", scorer.Name(), err))
+			continue
+		}
+		if score <= 0 {
+			continue
+		}
+		result.Score += score
+		result.Signals = append(result.Signals, Signal{Rule: scorer.Name(), Score: score, Reason: reason})
+	}
+
+	switch {
+	case c.Reject > 0 && result.Score >= c.Reject:
+		result.Action = filter.ActionReject
+	case c.Hold > 0 && result.Score >= c.Hold:
+		result.Action = filter.ActionHold
+	}
+	return result, errors.Join(errs...)
+}
+
+// ParseRules 讀取以逗號分隔的規則名稱，例如 "duplicate,links"；未知的名稱會回傳錯誤
+func ParseRules(value string) (map[string]bool, error) {
+	rules := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !isRule(name) {
+			return nil, fmt.Errorf("spam: unknown rule %q", name)
+		}
+		rules[name] = true
+	}
+	return rules, nil
+}
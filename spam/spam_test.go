@@ -0,0 +1,150 @@
+package spam_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/m/filter"
+	"example.com/m/spam"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func score(t *testing.T, scorer spam.SpamScorer, message spam.Message) float64 {
+	t.Helper()
+	score, _, err := scorer.Score(context.Background(), message)
+	require.NoError(t, err)
+	return score
+}
+
+func TestLinkScorer(t *testing.T) {
+	scorer := &spam.LinkScorer{MaxLinks: 2, NewAccountAge: 24 * time.Hour, NewAccountMaxLinks: 0, Weight: 2}
+	links := "see https://a.example https://b.example https://c.example"
+
+	assert.Equal(t, 2.0, score(t, scorer, spam.Message{Content: links, AccountAge: 30 * 24 * time.Hour}))
+	assert.Equal(t, 6.0, score(t, scorer, spam.Message{Content: links, AccountAge: time.Hour}))
+	assert.Equal(t, 2.0, score(t, scorer, spam.Message{Content: links})) // 帳號年齡未知時視為一般帳號
+	assert.Zero(t, score(t, scorer, spam.Message{Content: "no links here", AccountAge: time.Hour}))
+}
+
+func TestCapsScorer(t *testing.T) {
+	scorer := &spam.CapsScorer{MinLetters: 10, MaxRatio: 0.7, Weight: 3}
+
+	assert.Equal(t, 3.0, score(t, scorer, spam.Message{Content: "BUY CHEAP FOLLOWERS NOW"}))
+	assert.Zero(t, score(t, scorer, spam.Message{Content: "OK LOL"}))                       // 字母太少
+	assert.Zero(t, score(t, scorer, spam.Message{Content: "Meeting with NASA and the UN"})) // 比例未超過
+	assert.Zero(t, score(t, scorer, spam.Message{Content: "今天天氣很好今天天氣很好"}))                 // 沒有大小寫
+}
+
+func TestRepeatScorer(t *testing.T) {
+	scorer := &spam.RepeatScorer{MaxRun: 8, Weight: 2}
+
+	assert.Equal(t, 2.0, score(t, scorer, spam.Message{Content: "nooooooooooooo"}))
+	assert.Equal(t, 2.0, score(t, scorer, spam.Message{Content: "哈哈哈哈哈哈哈哈哈哈"}))
+	assert.Zero(t, score(t, scorer, spam.Message{Content: "haha 哈哈哈"}))
+	assert.Zero(t, score(t, scorer, spam.Message{Content: "indented" + strings.Repeat(" ", 20) + "text"}))
+}
+
+func TestEmojiScorer(t *testing.T) {
+	scorer := &spam.EmojiScorer{MaxEmoji: 5, Weight: 2}
+
+	assert.Equal(t, 2.0, score(t, scorer, spam.Message{Content: "🎉🎉🎉🔥🔥🔥"}))
+	assert.Zero(t, score(t, scorer, spam.Message{Content: "👍🏽👍🏽👍🏽👍🏽👍🏽"})) // 膚色修飾不重複計算
+	assert.Zero(t, score(t, scorer, spam.Message{Content: "nice 🎉"}))
+}
+
+func TestMentionScorer(t *testing.T) {
+	scorer := &spam.MentionScorer{MaxMentions: 3, Weight: 1}
+
+	assert.Equal(t, 2.0, score(t, scorer, spam.Message{Content: "@a @b @c @d @e hi"}))
+	assert.Zero(t, score(t, scorer, spam.Message{Content: "@a @A @a @b hi"}))                       // 同一用戶只計一次
+	assert.Zero(t, score(t, scorer, spam.Message{Content: "mail a@x.com b@x.com c@x.com d@x.com"})) // 電子郵件不是提及
+}
+
+func TestDuplicateScorer(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := spam.NewMemoryHistory()
+	history.Now = func() time.Time { return now }
+	scorer := &spam.DuplicateScorer{History: history, Window: time.Minute, MaxRepeats: 2, MinLength: 5, Weight: 2}
+	message := spam.Message{Sender: "alice", Room: "lobby", Content: "Join my server now"}
+
+	// 同一房間允許重複 MaxRepeats 次，大小寫與空白的差異視為相同內容
+	assert.Zero(t, score(t, scorer, message))
+	assert.Zero(t, score(t, scorer, spam.Message{Sender: "alice", Room: "lobby", Content: "join  my SERVER now"}))
+	assert.Equal(t, 2.0, score(t, scorer, message))
+
+	// 發送到其他房間另外加分
+	message.Room = "random"
+	assert.Equal(t, 6.0, score(t, scorer, message))
+
+	// 其他用戶與過短的訊息不受影響
+	assert.Zero(t, score(t, scorer, spam.Message{Sender: "bob", Room: "lobby", Content: "Join my server now"}))
+	for i := 0; i < 5; i++ {
+		assert.Zero(t, score(t, scorer, spam.Message{Sender: "alice", Room: "lobby", Content: "ok"}))
+	}
+
+	// 超過 Window 後重新計算
+	now = now.Add(2 * time.Minute)
+	assert.Zero(t, score(t, scorer, message))
+}
+
+type fixedScorer struct {
+	name  string
+	score float64
+	err   error
+}
+
+func (s fixedScorer) Name() string { return s.name }
+
+func (s fixedScorer) Score(ctx context.Context, message spam.Message) (float64, string, error) {
+	return s.score, s.name, s.err
+}
+
+func TestChainEvaluate(t *testing.T) {
+	ctx := context.Background()
+	failure := errors.New("redis down")
+
+	tests := []struct {
+		name    string
+		scorers []spam.SpamScorer
+		action  string
+		score   float64
+		rules   []string
+	}{
+		{"clean", []spam.SpamScorer{fixedScorer{"a", 0, nil}}, filter.ActionAllow, 0, []string{}},
+		{"below hold", []spam.SpamScorer{fixedScorer{"a", 2, nil}, fixedScorer{"b", 2, nil}}, filter.ActionAllow, 4, []string{"a", "b"}},
+		{"hold", []spam.SpamScorer{fixedScorer{"a", 3, nil}, fixedScorer{"b", 2, nil}}, filter.ActionHold, 5, []string{"a", "b"}},
+		{"reject", []spam.SpamScorer{fixedScorer{"a", 6, nil}, fixedScorer{"b", 4, nil}}, filter.ActionReject, 10, []string{"a", "b"}},
+		{"failing rule skipped", []spam.SpamScorer{fixedScorer{"a", 9, failure}, fixedScorer{"b", 5, nil}}, filter.ActionHold, 5, []string{"b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := spam.NewChain(5, 10, tt.scorers...).Evaluate(ctx, spam.Message{})
+			if tt.name == "failing rule skipped" {
+				assert.ErrorIs(t, err, failure)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.action, result.Action)
+			assert.Equal(t, tt.score, result.Score)
+			assert.Equal(t, tt.rules, result.Rules())
+		})
+	}
+
+	// 門檻為 0 表示不使用該處理方式
+	result, err := spam.NewChain(0, 0, fixedScorer{"a", 100, nil}).Evaluate(ctx, spam.Message{})
+	require.NoError(t, err)
+	assert.Equal(t, filter.ActionAllow, result.Action)
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := spam.ParseRules(" Links, caps ,,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{spam.RuleLinks: true, spam.RuleCaps: true}, rules)
+
+	_, err = spam.ParseRules("links,bogus")
+	assert.Error(t, err)
+}
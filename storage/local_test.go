@@ -0,0 +1,42 @@
+package storage_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"example.com/m/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStorageSaveOpenDelete(t *testing.T) {
+	s, err := storage.NewLocalStorage(t.TempDir())
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	n, err := s.Save(ctx, "ab/cdef", strings.NewReader("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+
+	r, err := s.Open(ctx, "ab/cdef")
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "hello", string(data))
+
+	assert.NoError(t, s.Delete(ctx, "ab/cdef"))
+	_, err = s.Open(ctx, "ab/cdef")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	// 刪除不存在的物件不應報錯
+	assert.NoError(t, s.Delete(ctx, "ab/cdef"))
+}
+
+func TestLocalStorageRejectsTraversal(t *testing.T) {
+	s, err := storage.NewLocalStorage(t.TempDir())
+	assert.NoError(t, err)
+
+	_, err = s.Save(context.Background(), "../escape", strings.NewReader("x"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound 表示指定的物件不存在
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage 定義附件二進位內容的存放介面，目前提供本地磁碟實作，之後可擴充 S3 相容實作
+type Storage interface {
+	// Save 將 r 的內容寫入 key，回傳寫入的位元組數
+	Save(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Open 開啟 key 對應的內容，呼叫者負責關閉
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 刪除 key 對應的內容，不存在時不視為錯誤
+	Delete(ctx context.Context, key string) error
+}
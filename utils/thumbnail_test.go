@@ -0,0 +1,52 @@
+package utils_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"example.com/m/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateThumbnail(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for x := 0; x < 400; x++ {
+		for y := 0; y < 200; y++ {
+			src.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, src))
+
+	thumbnail, err := utils.GenerateThumbnail(&buf, 100, 1_000_000)
+	require.NoError(t, err)
+
+	decoded, err := jpeg.Decode(bytes.NewReader(thumbnail))
+	require.NoError(t, err)
+	assert.Equal(t, 100, decoded.Bounds().Dx())
+	assert.Equal(t, 50, decoded.Bounds().Dy())
+}
+
+func TestGenerateThumbnail_OversizedHeader(t *testing.T) {
+	// 1x1 的 GIF，將檔頭宣告的畫布尺寸改為 50000x50000
+	var buf bytes.Buffer
+	require.NoError(t, gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black}), nil))
+	data := buf.Bytes()
+	binary.LittleEndian.PutUint16(data[6:8], 50000)
+	binary.LittleEndian.PutUint16(data[8:10], 50000)
+
+	_, err := utils.GenerateThumbnail(bytes.NewReader(data), 100, 40_000_000)
+	assert.ErrorIs(t, err, utils.ErrImageTooLarge)
+}
+
+func TestGenerateThumbnail_InvalidImage(t *testing.T) {
+	_, err := utils.GenerateThumbnail(bytes.NewReader([]byte("not an image")), 100, 1_000_000)
+	assert.Error(t, err)
+}